@@ -0,0 +1,216 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+)
+
+// Exit codes of goconfig.
+const (
+	exitOK = 0
+	// exitError is the status of the failures of generation, e.g. invalid templates or unwritable output.
+	exitError = 1
+	// exitUsage is the status of the invalid flags or spec.
+	exitUsage = 2
+	// exitBreaking is the status of compat if there are breaking changes, and of check if the output is outdated.
+	exitBreaking = 3
+)
+
+// usageError is the error of the flags, the spec or the arguments.
+type usageError struct {
+	err error
+}
+
+func (e *usageError) Error() string { return e.err.Error() }
+func (e *usageError) Unwrap() error { return e.err }
+
+func newUsageError(format string, v ...any) error {
+	return &usageError{err: fmt.Errorf(format, v...)}
+}
+
+var (
+	// errFlagReported is the error of parsing the flags, already reported by the flag package.
+	errFlagReported = errors.New("invalid flags")
+	// errBreaking is returned by compat if there are breaking changes.
+	errBreaking = errors.New("breaking changes found")
+	// errOutdated is returned by check if the generated files are outdated.
+	errOutdated = errors.New("outdated")
+)
+
+// command is the subcommand of goconfig.
+type command struct {
+	name string
+	// synopsis is the usage line without "goconfig".
+	synopsis string
+	// summary is shown in the list of the commands.
+	summary string
+	// description is shown in the help of the command.
+	description string
+	// spec is true if the command reads the spec by -field.
+	spec bool
+	run  func(fs *flag.FlagSet, args []string) error
+}
+
+func (c *command) usage(fs *flag.FlagSet) func() {
+	return func() {
+		w := fs.Output()
+		fmt.Fprintf(w, "Usage of goconfig %s:\n  goconfig %s\n\n%s\n", c.name, c.synopsis, c.description)
+		if c.spec {
+			fmt.Fprintf(w, "\n%s\n", specUsage)
+		}
+		fmt.Fprintln(w, "\nFlags:")
+		fs.PrintDefaults()
+	}
+}
+
+var commands = []*command{
+	{
+		name:     "gen",
+		synopsis: "gen [flags] -field F [directory]",
+		summary:  "generate the config; the default command",
+		description: `Generate the config to config.go in the directory of the package.
+"goconfig gen" can be omitted.`,
+		spec: true,
+		run:  runGen,
+	},
+	{
+		name:     "check",
+		synopsis: "check [flags] -field F [directory]",
+		summary:  "check that the generated files are up to date",
+		description: `Check that the files generated by gen with the same flags are up to date
+by generating them in memory, e.g. in CI.
+Exit with status 3 if any of them is outdated.`,
+		spec: true,
+		run:  runCheck,
+	},
+	{
+		name:        "doc",
+		synopsis:    "doc [flags] -field F",
+		summary:     "render the reference documentation",
+		description: "Render the reference documentation of the config as Markdown or a man page.",
+		spec:        true,
+		run:         runDoc,
+	},
+	{
+		name:     "schema",
+		synopsis: "schema [flags] -field F [directory]",
+		summary:  "print the JSON Schema",
+		description: `Print the JSON Schema of the config documents.
+The named types are resolved in the package of directory, the current directory by default.`,
+		spec: true,
+		run:  runSchema,
+	},
+	{
+		name:     "example",
+		synopsis: "example [flags] -field F [directory]",
+		summary:  "print the example JSON or .env",
+		description: `Print the example of the config with the default values as JSON or .env.
+The named types are resolved in the package of directory, the current directory by default.`,
+		spec: true,
+		run:  runExample,
+	},
+	{
+		name:     "compat",
+		synopsis: "compat -old OLD -new NEW",
+		summary:  "report the breaking changes between two specs",
+		description: `Report the changes from OLD to NEW, breaking or not: the generated API
+like WithXXX options and builder methods, the defaults, the secrets and
+the names read by the loaders.
+OLD and NEW are the spec models in JSON, or the config.go generated by goconfig.
+Exit with status 3 if there are breaking changes.`,
+		run: runCompat,
+	},
+	{
+		name:        "model",
+		synopsis:    "model [flags] -field F [directory]",
+		summary:     "print the model as JSON",
+		description: "Print the model of the config as JSON, the same as gen -emit-model.",
+		spec:        true,
+		run:         runModel,
+	},
+}
+
+func lookupCommand(name string) (*command, bool) {
+	for _, c := range commands {
+		if c.name == name {
+			return c, true
+		}
+	}
+	return nil, false
+}
+
+// runMain runs the command of args, gen if not given, and returns the exit status.
+func runMain(args []string) int {
+	c, _ := lookupCommand("gen")
+	bare := true
+	if len(args) > 0 {
+		if x, ok := lookupCommand(args[0]); ok {
+			c = x
+			args = args[1:]
+			bare = false
+		}
+	}
+
+	fs := flag.NewFlagSet("goconfig "+c.name, flag.ContinueOnError)
+	fs.Usage = c.usage(fs)
+	if bare {
+		fs.Usage = func() {
+			Usage(fs)
+		}
+	}
+
+	err := c.run(fs, args)
+	var uerr *usageError
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+		return exitOK
+	case errors.Is(err, errFlagReported):
+		return exitUsage
+	case errors.As(err, &uerr):
+		log.Print(err)
+		fmt.Fprintf(fs.Output(), "Run 'goconfig %s -h' for usage.\n", c.name)
+		return exitUsage
+	case errors.Is(err, errBreaking), errors.Is(err, errOutdated):
+		log.Print(err)
+		return exitBreaking
+	default:
+		log.Print(err)
+		return exitError
+	}
+}
+
+// parseFlags parses args by fs.
+// The errors are reported by fs except flag.ErrHelp, which is returned as it is.
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return errFlagReported
+	}
+	return nil
+}
+
+// Usage writes the usage of goconfig and the flags of gen.
+func Usage(fs *flag.FlagSet) {
+	w := fs.Output()
+	fmt.Fprintln(w, usage)
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-8s %s\n", c.name, c.summary)
+	}
+	fmt.Fprintf(w, "\nRun 'goconfig COMMAND -h' for the flags of the command.\n\n%s\n\n%s\n\nFlags of gen:\n", specUsage, envUsage)
+	fs.PrintDefaults()
+}
+
+const usage = `Usage of goconfig:
+  goconfig [gen] [flags] -field F [directory]
+  goconfig check [flags] -field F [directory]
+  goconfig doc [flags] -field F
+  goconfig schema [flags] -field F
+  goconfig example [flags] -field F
+  goconfig model [flags] -field F [directory]
+  goconfig compat -old OLD -new NEW
+
+Commands:`
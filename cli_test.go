@@ -0,0 +1,185 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunMain(t *testing.T) {
+	dir := t.TempDir()
+	writeModel := func(name, content string) string {
+		p := filepath.Join(dir, name)
+		assert.Nil(t, os.WriteFile(p, []byte(content), 0600))
+		return p
+	}
+	oldModel := writeModel("old.json", `{"config":"Config","configItem":"Item","configBuilder":"Builder","configOption":"Option","fields":[{"name":"Size","type":"int"}]}`)
+	addedModel := writeModel("added.json", `{"config":"Config","configItem":"Item","configBuilder":"Builder","configOption":"Option","fields":[{"name":"Size","type":"int"},{"name":"Name","type":"string"}]}`)
+	changedModel := writeModel("changed.json", `{"config":"Config","configItem":"Item","configBuilder":"Builder","configOption":"Option","fields":[{"name":"Size","type":"string"}]}`)
+	pkg := newTestPackage(t, dir)
+	assert.Nil(t, os.WriteFile(filepath.Join(pkg, "LICENSE"), []byte("Copyright 2026 Example\n"), 0600))
+	templates := filepath.Join(dir, "templates")
+	assert.Nil(t, os.Mkdir(templates, 0755))
+	assert.Nil(t, os.WriteFile(filepath.Join(templates, "item.tmpl"), []byte("{{.Unknown}}"), 0600))
+	assert.Nil(t, os.WriteFile(filepath.Join(pkg, "db.go"), []byte("package pkg\n\ntype DBConfig struct {\n\tHost string\n}\n"), 0600))
+
+	for _, tc := range []struct {
+		name string
+		args []string
+		want int
+	}{
+		{name: "help", args: []string{"-h"}, want: exitOK},
+		{name: "command help", args: []string{"schema", "-h"}, want: exitOK},
+		{name: "unknown flag", args: []string{"-unknown"}, want: exitUsage},
+		{name: "no field", args: []string{"-option"}, want: exitUsage},
+		{name: "invalid field", args: []string{"-field", "Size"}, want: exitUsage},
+		{name: "tx without option", args: []string{"gen", "-field", "Size int", "-tx"}, want: exitUsage},
+		{name: "dir file collision", args: []string{"-field", "Size int @alias=Old_Size|OldSize int", "-dir"}, want: exitUsage},
+		{name: "schema", args: []string{"schema", "-field", "Size int", "-output", filepath.Join(dir, "schema.json")}, want: exitOK},
+		{name: "schema named type", args: []string{"schema", "-field", "DB DBConfig", "-output", filepath.Join(dir, "db.schema.json")}, want: exitOK},
+		{name: "example", args: []string{"example", "-field", "Size int", "-format", "env", "-output", filepath.Join(dir, ".env")}, want: exitOK},
+		{name: "example unknown format", args: []string{"example", "-field", "Size int", "-format", "yaml"}, want: exitUsage},
+		{name: "doc", args: []string{"doc", "-field", "Size int", "-output", filepath.Join(dir, "doc.md")}, want: exitOK},
+		{name: "missing plugin", args: []string{"check", "-field", "Size int", "-plugin", "missing"}, want: exitError},
+		{name: "invalid templates", args: []string{"check", "-field", "Size int", "-templates", filepath.Join(dir, "none")}, want: exitError},
+		{name: "template error", args: []string{"check", "-field", "Size int", "-templates", templates}, want: exitError},
+		{name: "compat", args: []string{"compat", "-old", oldModel, "-new", addedModel}, want: exitOK},
+		{name: "compat breaking", args: []string{"compat", "-old", oldModel, "-new", changedModel}, want: exitBreaking},
+		{name: "compat missing file", args: []string{"compat", "-old", oldModel, "-new", filepath.Join(dir, "none.json")}, want: exitError},
+		{name: "compat no new", args: []string{"compat", "-old", oldModel}, want: exitUsage},
+		{name: "check missing", args: []string{"check", "-field", "Size int"}, want: exitBreaking},
+		{name: "gen", args: []string{"-field", "Size int"}, want: exitOK},
+		{name: "check", args: []string{"check", "-field", "Size int"}, want: exitOK},
+		{name: "check outdated", args: []string{"check", "-field", "Size int|Name string"}, want: exitBreaking},
+		{name: "gen split", args: []string{"-field", "Size int", "-option", "-split"}, want: exitOK},
+		{name: "check split", args: []string{"check", "-field", "Size int", "-option", "-split"}, want: exitOK},
+		{name: "gen tags and header", args: []string{"-field", "Size int", "-tags", "linux", "-header", "LICENSE"}, want: exitOK},
+		{name: "compat generated", args: []string{"compat", "-old", "config.go", "-new", "config.go"}, want: exitOK},
+		{name: "invalid tags", args: []string{"-field", "Size int", "-tags", "linux &&"}, want: exitUsage},
+		{name: "missing directory", args: []string{"-field", "Size int", filepath.Join(dir, "none")}, want: exitUsage},
+		{name: "check missing directory", args: []string{"check", "-field", "Size int", filepath.Join(dir, "none")}, want: exitUsage},
+		{name: "model missing directory", args: []string{"model", "-field", "Size int", filepath.Join(dir, "none")}, want: exitUsage},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, runMain(tc.args))
+		})
+	}
+
+	for _, name := range []string{"schema.json", ".env", "doc.md"} {
+		_, err := os.Stat(filepath.Join(dir, name))
+		assert.Nil(t, err, name)
+	}
+	_, err := os.Stat(filepath.Join(pkg, "config.go"))
+	assert.Nil(t, err)
+	b, err := os.ReadFile(filepath.Join(dir, "db.schema.json"))
+	assert.Nil(t, err)
+	assert.Contains(t, string(b), `"Host"`, "nested properties")
+}
+
+// newTestPackage creates a module in dir and changes the working directory to it.
+func newTestPackage(t *testing.T, dir string) string {
+	t.Helper()
+	pkg := filepath.Join(dir, "pkg")
+	assert.Nil(t, os.Mkdir(pkg, 0755))
+	assert.Nil(t, os.WriteFile(filepath.Join(pkg, "go.mod"), []byte("module example.com/pkg\n\ngo 1.22\n"), 0600))
+	assert.Nil(t, os.WriteFile(filepath.Join(pkg, "pkg.go"), []byte("package pkg\n"), 0600))
+	t.Chdir(pkg) // load the package
+	return pkg
+}
+
+func TestRunMainSplit(t *testing.T) {
+	pkg := newTestPackage(t, t.TempDir())
+	exists := func(name string) bool {
+		_, err := os.Stat(filepath.Join(pkg, name))
+		return err == nil
+	}
+	sections := []string{"config_item.go", "config_builder.go", "config_option.go", "config_env.go"}
+	split := []string{"-field", "Size int", "-option", "-env", "-split"}
+
+	assert.Equal(t, exitOK, runMain(split))
+	for _, name := range sections {
+		assert.True(t, exists(name), name)
+	}
+	assert.Equal(t, exitOK, runMain(append([]string{"check"}, split...)))
+
+	// not generated by goconfig, or not a section of config.go
+	assert.Nil(t, os.WriteFile(filepath.Join(pkg, "config_reset.go"), []byte("package pkg\n"), 0600))
+	assert.Equal(t, exitOK, runMain([]string{"-field", "Name string", "-output", "config_server.go"}))
+
+	t.Run("stdout", func(t *testing.T) {
+		t.Setenv("GOCONFIG_STDOUT", "1")
+		assert.Nil(t, os.Remove(filepath.Join(pkg, "config_env.go")))
+		out := runMainStdout(t, []string{"-field", "Size int", "-option", "-split"})
+		for _, name := range []string{"config.go", "config_item.go", "config_builder.go", "config_option.go"} {
+			assert.Contains(t, out, "==> "+name+" <==\n")
+		}
+		assert.False(t, exists("config_env.go"), "nothing is written")
+		assert.True(t, exists("config_item.go"), "nothing is removed")
+	})
+
+	t.Run("check stale", func(t *testing.T) {
+		assert.Equal(t, exitBreaking, runMain([]string{"check", "-field", "Size int", "-option"}))
+	})
+
+	t.Run("unsplit", func(t *testing.T) {
+		assert.Equal(t, exitOK, runMain([]string{"-field", "Size int", "-option"}))
+		for _, name := range sections {
+			assert.False(t, exists(name), name)
+		}
+		for _, name := range []string{"config.go", "config_reset.go", "config_server.go"} {
+			assert.True(t, exists(name), name)
+		}
+		assert.Equal(t, exitOK, runMain([]string{"check", "-field", "Size int", "-option"}))
+	})
+}
+
+func TestRunMainSplitConfigs(t *testing.T) {
+	pkg := newTestPackage(t, t.TempDir())
+	exists := func(name string) bool {
+		_, err := os.Stat(filepath.Join(pkg, name))
+		return err == nil
+	}
+	env := []string{"-field", "Port int", "-config", "EnvConfig", "-configItem", "EnvConfigItem", "-configBuilder", "EnvConfigBuilder", "-output", "config_env.go"}
+	splitEnv := append(slices.Clone(env), "-split")
+
+	for _, tc := range []struct {
+		name string
+		env  []string
+	}{
+		{name: "other config", env: env},
+		{name: "other split config", env: splitEnv},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, exitOK, runMain(tc.env))
+			// config_env.go is not the section env of config.go
+			assert.Equal(t, exitOK, runMain([]string{"-field", "Size int", "-split"}))
+			assert.Equal(t, exitOK, runMain([]string{"check", "-field", "Size int", "-split"}))
+			assert.Equal(t, exitOK, runMain([]string{"-field", "Size int"}))
+			assert.Equal(t, exitOK, runMain([]string{"check", "-field", "Size int"}))
+			assert.False(t, exists("config_item.go"))
+			assert.True(t, exists("config_env.go"))
+			assert.Equal(t, exitOK, runMain(append([]string{"check"}, tc.env...)))
+		})
+	}
+}
+
+// runMainStdout runs gen with args and returns the stdout.
+func runMainStdout(t *testing.T, args []string) string {
+	t.Helper()
+	f, err := os.CreateTemp(t.TempDir(), "stdout")
+	if !assert.Nil(t, err) {
+		return ""
+	}
+	defer f.Close()
+	stdout := os.Stdout
+	os.Stdout = f
+	code := runMain(args)
+	os.Stdout = stdout
+	assert.Equal(t, exitOK, code)
+	b, err := os.ReadFile(f.Name())
+	assert.Nil(t, err)
+	return string(b)
+}
@@ -0,0 +1,374 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+func runCompat(fs *flag.FlagSet, args []string) error {
+	var (
+		oldFile = fs.String("old", "", "old spec; must be set")
+		newFile = fs.String("new", "", "new spec; must be set")
+	)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	if *oldFile == "" || *newFile == "" {
+		return newUsageError("old and new option must be set")
+	}
+	oldSpec, err := readSpec(*oldFile)
+	if err != nil {
+		return fmt.Errorf("failed to read old spec: %w", err)
+	}
+	newSpec, err := readSpec(*newFile)
+	if err != nil {
+		return fmt.Errorf("failed to read new spec: %w", err)
+	}
+	changes, err := compareSpecs(oldSpec, newSpec)
+	if err != nil {
+		return err
+	}
+
+	var breaking bool
+	for _, c := range changes {
+		fmt.Println(c)
+		breaking = breaking || c.breaking
+	}
+	if breaking {
+		return errBreaking
+	}
+	return nil
+}
+
+// readSpec reads the model, or the spec from the header of the generated file if it is a Go file.
+func readSpec(fileName string) (*specModel, error) {
+	if filepath.Ext(fileName) != ".go" {
+		return readModel(fileName)
+	}
+	args, ok, err := readGeneratedArgs(fileName)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("%s is not generated by goconfig", fileName)
+	}
+	s, err := parseHeaderArgs(args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fileName, err)
+	}
+	return s, nil
+}
+
+// parseHeaderArgs parses the command line in the header of the generated file, quoted like shell words.
+func parseHeaderArgs(line string) (*specModel, error) {
+	_, spec, _, err := parseHeaderFlags(line)
+	if err != nil {
+		return nil, err
+	}
+	return spec.model()
+}
+
+type compatChange struct {
+	breaking bool
+	message  string
+}
+
+func (s compatChange) String() string {
+	if s.breaking {
+		return "breaking: " + s.message
+	}
+	return "non-breaking: " + s.message
+}
+
+type compatChanges []compatChange
+
+func (s *compatChanges) add(breaking bool, format string, v ...any) {
+	*s = append(*s, compatChange{
+		breaking: breaking,
+		message:  fmt.Sprintf(format, v...),
+	})
+}
+
+// compareSpecs returns the changes from oldSpec to newSpec, the breaking changes first.
+func compareSpecs(oldSpec, newSpec *specModel) ([]compatChange, error) {
+	oldGen, err := oldSpec.newGenerator()
+	if err != nil {
+		return nil, fmt.Errorf("old: %w", err)
+	}
+	newGen, err := newSpec.newGenerator()
+	if err != nil {
+		return nil, fmt.Errorf("new: %w", err)
+	}
+	var r compatChanges
+	compareFields(&r, oldGen, newGen)
+	if err := compareAPI(&r, oldGen, newGen); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(r, func(i, j int) bool { return r[i].breaking && !r[j].breaking })
+	return r, nil
+}
+
+// compareFields compares the fields and the names read by the loaders.
+func compareFields(r *compatChanges, oldGen, newGen *generator) {
+	byName := map[string]*configField{}
+	for _, f := range newGen.conf.fields {
+		byName[f.fieldName] = f
+		for _, a := range f.aliases() {
+			byName[a] = f
+		}
+	}
+	for _, o := range oldGen.conf.fields {
+		n, ok := byName[o.fieldName]
+		if !ok {
+			r.add(true, "field %s is removed", o.fieldName)
+			continue
+		}
+		if n.fieldName != o.fieldName {
+			r.add(false, "field %s is renamed to %s with alias", o.fieldName, n.fieldName)
+		}
+		if o.typeName != n.typeName {
+			r.add(true, "field %s: type is changed from %s to %s", o.fieldName, o.typeName, n.typeName)
+		}
+		oldDefault, _ := o.defaultValue()
+		newDefault, _ := n.defaultValue()
+		switch {
+		case oldDefault == newDefault:
+		case o.isSecret() || n.isSecret():
+			r.add(true, "field %s: default is changed", o.fieldName)
+		default:
+			r.add(true, "field %s: default is changed from %s to %s", o.fieldName, orZero(oldDefault), orZero(newDefault))
+		}
+		switch {
+		case o.isSecret() && !n.isSecret():
+			r.add(true, "field %s is no longer secret", o.fieldName)
+		case !o.isSecret() && n.isSecret():
+			r.add(false, "field %s becomes secret", o.fieldName)
+		}
+		if _, ok := n.deprecated(); ok {
+			if _, ok := o.deprecated(); !ok {
+				r.add(false, "field %s is deprecated", o.fieldName)
+			}
+		}
+	}
+	oldNames := map[string]bool{}
+	for _, f := range oldGen.conf.fields {
+		oldNames[f.fieldName] = true
+	}
+	for _, f := range newGen.conf.fields {
+		if !oldNames[f.fieldName] {
+			renamed := false
+			for _, a := range f.aliases() {
+				renamed = renamed || oldNames[a]
+			}
+			if !renamed {
+				r.add(false, "field %s is added", f.fieldName)
+			}
+		}
+	}
+
+	compareNames(r, "environment variable", loaderEnvNames(oldGen), loaderEnvNames(newGen))
+	compareNames(r, "file", loaderFileNames(oldGen), loaderFileNames(newGen))
+	compareNames(r, "JSON key", loaderJSONKeys(oldGen), loaderJSONKeys(newGen))
+
+	oldVersion, newVersion := oldGen.opts.schemaVersion, newGen.opts.schemaVersion
+	switch {
+	case oldVersion > 0 && newVersion < oldVersion:
+		r.add(true, "schemaVersion is changed from %d to %d, the documents of version %d are rejected", oldVersion, newVersion, oldVersion)
+	case oldVersion > 0 && newVersion > oldVersion:
+		r.add(false, "schemaVersion is changed from %d to %d, the documents of version %d are migrated", oldVersion, newVersion, oldVersion)
+	}
+}
+
+func orZero(v string) string {
+	if v == "" {
+		return "zero value"
+	}
+	return v
+}
+
+// compareNames reports the names no longer read by the loaders.
+func compareNames(r *compatChanges, kind string, oldNames, newNames []string) {
+	set := map[string]bool{}
+	for _, x := range newNames {
+		set[x] = true
+	}
+	for _, x := range oldNames {
+		if !set[x] {
+			r.add(true, "%s %s is no longer read", kind, x)
+		}
+	}
+}
+
+func loaderEnvNames(g *generator) []string {
+	if g.env == nil {
+		return nil
+	}
+	var r []string
+	for _, f := range g.conf.fields {
+		for _, x := range append([]string{f.fieldName}, f.aliases()...) {
+			name := g.env.envNameOf(x)
+			r = append(r, name)
+			if f.isSecret() {
+				r = append(r, name+"_FILE")
+			}
+		}
+	}
+	return r
+}
+
+func loaderFileNames(g *generator) []string {
+	if g.dir == nil {
+		return nil
+	}
+	var r []string
+	for _, f := range g.conf.fields {
+		for _, x := range append([]string{f.fieldName}, f.aliases()...) {
+			r = append(r, fileKeysOf(x)...)
+		}
+	}
+	return r
+}
+
+func loaderJSONKeys(g *generator) []string {
+	if g.jsonLoader == nil {
+		return nil
+	}
+	var r []string
+	for _, f := range g.conf.fields {
+		if _, ok := typeSchema(f.typeExpr, nil); ok {
+			r = append(r, append([]string{f.fieldName}, f.aliases()...)...)
+		}
+	}
+	return r
+}
+
+// compareAPI compares the exported declarations of the generated code.
+func compareAPI(r *compatChanges, oldGen, newGen *generator) error {
+	oldAPI, oldInterfaces, err := generatedAPI(oldGen)
+	if err != nil {
+		return fmt.Errorf("old: %w", err)
+	}
+	newAPI, _, err := generatedAPI(newGen)
+	if err != nil {
+		return fmt.Errorf("new: %w", err)
+	}
+	keys := make([]string, 0, len(oldAPI))
+	for k := range oldAPI {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		n, ok := newAPI[k]
+		switch {
+		case !ok:
+			r.add(true, "%s is removed", oldAPI[k])
+		case n != oldAPI[k]:
+			r.add(true, "%s is changed to %s", oldAPI[k], n)
+		}
+	}
+	keys = keys[:0]
+	for k := range newAPI {
+		if _, ok := oldAPI[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		// the implementations of the existing interface are broken
+		recv, _, _ := strings.Cut(strings.TrimPrefix(k, "method "), ".")
+		r.add(oldInterfaces[recv], "%s is added", newAPI[k])
+	}
+	return nil
+}
+
+// generatedAPI returns the exported declarations of the generated code by the keys like "func WithSize",
+// and the names of the interfaces.
+func generatedAPI(g *generator) (map[string]string, map[string]bool, error) {
+	var b bytes.Buffer
+	b.WriteString("package config\n")
+	if err := g.generate(); err != nil {
+		return nil, nil, err
+	}
+	b.Write(g.bytes())
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "config.go", b.Bytes(), 0)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	print := func(x any) string {
+		var b bytes.Buffer
+		_ = printer.Fprint(&b, fset, x)
+		return b.String()
+	}
+	var (
+		api        = map[string]string{}
+		interfaces = map[string]bool{}
+	)
+	for _, d := range f.Decls {
+		switch d := d.(type) {
+		case *ast.FuncDecl:
+			if !d.Name.IsExported() {
+				continue
+			}
+			sig := strings.TrimPrefix(print(d.Type), "func")
+			if d.Recv == nil {
+				api["func "+d.Name.Name] = "func " + d.Name.Name + sig
+				continue
+			}
+			recv := print(d.Recv.List[0].Type)
+			recvName := strings.TrimPrefix(strings.SplitN(recv, "[", 2)[0], "*")
+			api["method "+recvName+"."+d.Name.Name] = fmt.Sprintf("method (%s).%s%s", recv, d.Name.Name, sig)
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					if !spec.Name.IsExported() {
+						continue
+					}
+					name := spec.Name.Name
+					switch t := spec.Type.(type) {
+					case *ast.StructType:
+						api["type "+name] = "type " + name + " struct"
+						for _, field := range t.Fields.List {
+							for _, x := range field.Names {
+								if x.IsExported() {
+									api["field "+name+"."+x.Name] = fmt.Sprintf("field %s.%s %s", name, x.Name, print(field.Type))
+								}
+							}
+						}
+					case *ast.InterfaceType:
+						api["type "+name] = "type " + name + " interface"
+						interfaces[name] = true
+						for _, m := range t.Methods.List {
+							for _, x := range m.Names {
+								api["method "+name+"."+x.Name] = fmt.Sprintf("method %s.%s%s", name, x.Name, strings.TrimPrefix(print(m.Type), "func"))
+							}
+						}
+					default:
+						api["type "+name] = fmt.Sprintf("type %s %s", name, print(spec.Type))
+					}
+				case *ast.ValueSpec:
+					for _, x := range spec.Names {
+						if !x.IsExported() {
+							continue
+						}
+						// the values are not compared, schemaVersion is compared as the spec
+						key := d.Tok.String() + " " + x.Name
+						api[key] = key
+					}
+				}
+			}
+		}
+	}
+	return api, interfaces, nil
+}
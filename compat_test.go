@@ -0,0 +1,245 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseHeaderArgs(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		line string
+		want *specModel
+	}{
+		{
+			name: "flags",
+			line: `-env -envPrefix APP -field 'Size int @default=10 @doc="Size of the buffer."|Name string' -option`,
+			want: &specModel{
+				Config:        "Config",
+				ConfigItem:    "ConfigItem",
+				ConfigBuilder: "ConfigBuilder",
+				ConfigOption:  "ConfigOption",
+				Options: modelOptions{
+					Option:    true,
+					Env:       true,
+					EnvPrefix: "APP",
+				},
+				Fields: []modelField{
+					{
+						Name:    "Size",
+						Type:    "int",
+						Default: "10",
+						Tags: map[string]string{
+							"doc": "Size of the buffer.",
+						},
+					},
+					{
+						Name: "Name",
+						Type: "string",
+					},
+				},
+			},
+		},
+		{
+			name: "prefix and directory",
+			line: `-field 'Size int' -output config.go -prefix my ./pkg`,
+			want: &specModel{
+				Config:        "MyConfig",
+				ConfigItem:    "MyConfigItem",
+				ConfigBuilder: "MyConfigBuilder",
+				ConfigOption:  "MyConfigOption",
+				Fields: []modelField{
+					{
+						Name: "Size",
+						Type: "int",
+					},
+				},
+			},
+		},
+		{
+			name: "field is last",
+			line: `-option -field 'Size int' .`,
+			want: &specModel{
+				Config:        "Config",
+				ConfigItem:    "ConfigItem",
+				ConfigBuilder: "ConfigBuilder",
+				ConfigOption:  "ConfigOption",
+				Options: modelOptions{
+					Option: true,
+				},
+				Fields: []modelField{
+					{
+						Name: "Size",
+						Type: "int",
+					},
+				},
+			},
+		},
+		{
+			name: "quoted",
+			line: `-envPrefix APP -field 'Size int @doc="It'\''s the size."|Name string' -option -tags 'linux && !nogoconfig' .`,
+			want: &specModel{
+				Config:        "Config",
+				ConfigItem:    "ConfigItem",
+				ConfigBuilder: "ConfigBuilder",
+				ConfigOption:  "ConfigOption",
+				Options: modelOptions{
+					Option:    true,
+					EnvPrefix: "APP",
+				},
+				Fields: []modelField{
+					{
+						Name: "Size",
+						Type: "int",
+						Tags: map[string]string{
+							"doc": "It's the size.",
+						},
+					},
+					{
+						Name: "Name",
+						Type: "string",
+					},
+				},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseHeaderArgs(tc.line)
+			if !assert.Nil(t, err) {
+				return
+			}
+			assert.Equal(t, tc.want, got)
+		})
+	}
+
+	for _, line := range []string{
+		`-field Size int -option`,
+		`-field 'Size int`,
+		`-field 'Size int' -tx`,
+	} {
+		t.Run(line, func(t *testing.T) {
+			m, err := parseHeaderArgs(line)
+			if err == nil {
+				_, err = m.newGenerator()
+			}
+			assert.NotNil(t, err)
+		})
+	}
+}
+
+func TestCompareSpecs(t *testing.T) {
+	newModel := func(fields string, opts generatorOptions) *specModel {
+		fs, err := parseConfigFieldList(fields)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return newSpecModel(fs, "Config", "Item", "Builder", "Option", opts)
+	}
+
+	for _, tc := range []struct {
+		name     string
+		oldSpec  *specModel
+		newSpec  *specModel
+		want     []string
+		breaking bool
+	}{
+		{
+			name:    "same",
+			oldSpec: newModel("Size int", generatorOptions{option: true}),
+			newSpec: newModel("Size int", generatorOptions{option: true}),
+		},
+		{
+			name:    "added field",
+			oldSpec: newModel("Size int", generatorOptions{option: true}),
+			newSpec: newModel("Size int|Name string", generatorOptions{option: true}),
+			want: []string{
+				"non-breaking: field Name is added",
+				"non-breaking: field Config.Name *Item[string] is added",
+				"non-breaking: func WithName(v string) Option is added",
+				"non-breaking: method (*Builder).Name(v string) *Builder is added",
+			},
+		},
+		{
+			name:    "changed type and default",
+			oldSpec: newModel("Size int @default=10|Password string @secret @default=\"x\"", generatorOptions{}),
+			newSpec: newModel("Size int64 @default=10|Password string @secret @default=\"y\"", generatorOptions{}),
+			want: []string{
+				"breaking: field Size: type is changed from int to int64",
+				"breaking: field Password: default is changed",
+				"breaking: field Config.Size *Item[int] is changed to field Config.Size *Item[int64]",
+				"breaking: method (*Builder).Size(v int) *Builder is changed to method (*Builder).Size(v int64) *Builder",
+			},
+			breaking: true,
+		},
+		{
+			name:    "renamed without alias",
+			oldSpec: newModel("Size int", generatorOptions{option: true, env: true}),
+			newSpec: newModel("Length int", generatorOptions{option: true, env: true}),
+			want: []string{
+				"breaking: field Size is removed",
+				"breaking: environment variable SIZE is no longer read",
+				"breaking: field Config.Size *Item[int] is removed",
+				"breaking: func WithSize(v int) Option is removed",
+				"breaking: method (*Builder).Size(v int) *Builder is removed",
+				"non-breaking: field Length is added",
+				"non-breaking: field Config.Length *Item[int] is added",
+				"non-breaking: func WithLength(v int) Option is added",
+				"non-breaking: method (*Builder).Length(v int) *Builder is added",
+			},
+			breaking: true,
+		},
+		{
+			name:    "renamed with alias and accessor",
+			oldSpec: newModel("Size int", generatorOptions{option: true, env: true, accessor: true}),
+			newSpec: newModel("Length int @alias=Size", generatorOptions{option: true, env: true, accessor: true}),
+			want: []string{
+				"non-breaking: field Size is renamed to Length with alias",
+				"non-breaking: func WithLength(v int) Option is added",
+				"non-breaking: method (*Builder).Length(v int) *Builder is added",
+				"non-breaking: method (*Config).Length() int is added",
+				"non-breaking: var ConfigDeprecationLogger is added",
+			},
+		},
+		{
+			name:    "interface method",
+			oldSpec: newModel("Size int", generatorOptions{accessor: true, reader: true}),
+			newSpec: newModel("Size int|Name string", generatorOptions{accessor: true, reader: true}),
+			want: []string{
+				"breaking: method ConfigReader.Name() string is added",
+				"non-breaking: field Name is added",
+				"non-breaking: field FakeConfig.NameValue string is added",
+				"non-breaking: method (*Builder).Name(v string) *Builder is added",
+				"non-breaking: method (*Config).Name() string is added",
+				"non-breaking: method (*FakeConfig).Name() string is added",
+			},
+			breaking: true,
+		},
+		{
+			name:    "schema version",
+			oldSpec: newModel("Size int", generatorOptions{schemaVersion: 2}),
+			newSpec: newModel("Size int", generatorOptions{schemaVersion: 1}),
+			want: []string{
+				"breaking: schemaVersion is changed from 2 to 1, the documents of version 2 are rejected",
+			},
+			breaking: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := compareSpecs(tc.oldSpec, tc.newSpec)
+			if !assert.Nil(t, err) {
+				return
+			}
+			var (
+				messages []string
+				breaking bool
+			)
+			for _, c := range got {
+				messages = append(messages, c.String())
+				breaking = breaking || c.breaking
+			}
+			assert.Equal(t, tc.want, messages)
+			assert.Equal(t, tc.breaking, breaking)
+		})
+	}
+}
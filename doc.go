@@ -0,0 +1,156 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+)
+
+func runDoc(fs *flag.FlagSet, args []string) error {
+	var (
+		spec   = newSpecFlags(fs)
+		format = fs.String("format", "markdown", "output format; markdown or man")
+		output = fs.String("output", "", "output file name; default stdout")
+	)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	g, err := spec.newGenerator()
+	if err != nil {
+		return err
+	}
+	d := &configDoc{
+		config: g.conf,
+		option: g.option,
+		env:    g.env,
+	}
+	if !g.opts.option {
+		d.option = nil
+	}
+
+	var src string
+	switch *format {
+	case "markdown":
+		src = d.markdown()
+	case "man":
+		src = d.man()
+	default:
+		return newUsageError("unknown format: %s", *format)
+	}
+	return writeOutput(*output, []byte(src))
+}
+
+type configDoc struct {
+	config *config
+	option *configOption // nil if options are not generated
+	env    *configEnv    // nil if LoadEnv is not generated
+}
+
+type docProperty struct {
+	name  string
+	value string
+	code  bool // render value as code
+}
+
+func (s *configDoc) properties(f *configField) []docProperty {
+	xs := []docProperty{
+		{name: "Type", value: f.typeName, code: true},
+	}
+	if v, ok := f.defaultValue(); ok {
+		if f.isSecret() {
+			xs = append(xs, docProperty{name: "Default", value: redacted})
+		} else {
+			xs = append(xs, docProperty{name: "Default", value: v, code: true})
+		}
+	}
+	for _, c := range []struct {
+		tag  string
+		name string
+	}{
+		{tag: "min", name: "Minimum"},
+		{tag: "max", name: "Maximum"},
+		{tag: "enum", name: "Enum"},
+	} {
+		if v, ok := f.tags[c.tag]; ok {
+			xs = append(xs, docProperty{name: c.name, value: v, code: true})
+		}
+	}
+	if aliases := f.aliases(); len(aliases) > 0 {
+		xs = append(xs, docProperty{name: "Aliases", value: strings.Join(aliases, ", "), code: true})
+	}
+	if v, ok := f.deprecated(); ok {
+		xs = append(xs, docProperty{name: "Deprecated", value: v})
+	}
+	if s.env != nil {
+		name := s.env.envName(f)
+		xs = append(xs, docProperty{name: "Environment variable", value: name, code: true})
+		if f.isSecret() {
+			xs = append(xs, docProperty{name: "File environment variable", value: name + "_FILE", code: true})
+		}
+	}
+	if s.option != nil {
+		xs = append(xs, docProperty{name: "Option", value: fmt.Sprintf("With%s", f.fieldName), code: true})
+	}
+	if f.isSecret() {
+		xs = append(xs, docProperty{name: "Secret", value: "yes"})
+	}
+	return xs
+}
+
+func (s *configDoc) markdown() string {
+	var b stringBuilder
+	b.writef("# %s", s.config.typeName)
+	for _, f := range s.config.fields {
+		b.write("")
+		b.writef("## %s", f.fieldName)
+		b.write("")
+		if d := f.doc(); d != "" {
+			b.write(d)
+			b.write("")
+		}
+		for _, p := range s.properties(f) {
+			if p.code {
+				b.writef("- %s: `%s`", p.name, p.value)
+			} else {
+				b.writef("- %s: %s", p.name, p.value)
+			}
+		}
+	}
+	return b.String()
+}
+
+func (s *configDoc) man() string {
+	var b stringBuilder
+	b.writef(".TH %s 5", roffEscape(strings.ToUpper(s.config.typeName)))
+	b.write(".SH NAME")
+	b.writef("%s \\- configuration reference", roffEscape(s.config.typeName))
+	b.write(".SH FIELDS")
+	for _, f := range s.config.fields {
+		b.write(".TP")
+		b.writef(".B %s", roffEscape(f.fieldName))
+		if d := f.doc(); d != "" {
+			for _, x := range strings.Split(d, "\n") {
+				b.write(roffEscape(x))
+			}
+			b.write(".br")
+		}
+		for i, p := range s.properties(f) {
+			if i > 0 {
+				b.write(".br")
+			}
+			b.writef("%s: %s", p.name, roffEscape(p.value))
+		}
+	}
+	return b.String()
+}
+
+// roffEscape escapes v as a text line of roff.
+func roffEscape(v string) string {
+	v = strings.ReplaceAll(v, `\`, `\e`)
+	v = strings.ReplaceAll(v, "-", `\-`)
+	if strings.HasPrefix(v, ".") || strings.HasPrefix(v, "'") {
+		v = `\&` + v
+	}
+	return v
+}
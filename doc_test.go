@@ -0,0 +1,81 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDoc(t *testing.T) {
+	g := newGenerator(
+		`Size int @default=10 @doc="Size of the buffer."|Password string @secret @default="x"`,
+		"Config",
+		"Item",
+		"Builder",
+		"Option",
+		generatorOptions{
+			option:    true,
+			env:       true,
+			envPrefix: "APP",
+		},
+	)
+	d := &configDoc{
+		config: g.conf,
+		option: g.option,
+		env:    g.env,
+	}
+
+	t.Run("markdown", func(t *testing.T) {
+		assert.Equal(t, "# Config\n"+`
+## Size
+
+Size of the buffer.
+
+- Type: `+"`int`"+`
+- Default: `+"`10`"+`
+- Environment variable: `+"`APP_SIZE`"+`
+- Option: `+"`WithSize`"+`
+
+## Password
+
+- Type: `+"`string`"+`
+- Default: [REDACTED]
+- Environment variable: `+"`APP_PASSWORD`"+`
+- File environment variable: `+"`APP_PASSWORD_FILE`"+`
+- Option: `+"`WithPassword`"+`
+- Secret: yes
+`, d.markdown())
+	})
+
+	t.Run("man", func(t *testing.T) {
+		assert.Equal(t, `.TH CONFIG 5
+.SH NAME
+Config \- configuration reference
+.SH FIELDS
+.TP
+.B Size
+Size of the buffer.
+.br
+Type: int
+.br
+Default: 10
+.br
+Environment variable: APP_SIZE
+.br
+Option: WithSize
+.TP
+.B Password
+Type: string
+.br
+Default: [REDACTED]
+.br
+Environment variable: APP_PASSWORD
+.br
+File environment variable: APP_PASSWORD_FILE
+.br
+Option: WithPassword
+.br
+Secret: yes
+`, d.man())
+	})
+}
@@ -20,6 +20,7 @@ type endToEndTestcase struct {
 	configOptionType  string
 	needOption        bool
 	typePrefix        string
+	args              []string // additional arguments
 }
 
 func (tc *endToEndTestcase) test(t *testing.T, caseNumber int, g *goConfig) {
@@ -34,6 +35,7 @@ func (tc *endToEndTestcase) test(t *testing.T, caseNumber int, g *goConfig) {
 		tc.configOptionType,
 		tc.needOption,
 		tc.typePrefix,
+		tc.args,
 	)
 }
 
@@ -116,6 +118,166 @@ func TestEndToEnd(t *testing.T) {
 			needOption:        true,
 			typePrefix:        "Prefix",
 		},
+		{
+			name:              "freeze",
+			fileName:          "freeze.go",
+			field:             "Size int|Name string",
+			configType:        "Config",
+			configItemType:    "Item",
+			configBuilderType: "Builder",
+			configOptionType:  "Option",
+			needOption:        true,
+			args:              []string{"-freeze"},
+		},
+		{
+			name:              "tx",
+			fileName:          "tx.go",
+			field:             "Size int|Name string|Tags []string|Handler func()",
+			configType:        "Config",
+			configItemType:    "Item",
+			configBuilderType: "Builder",
+			configOptionType:  "Option",
+			needOption:        true,
+			args:              []string{"-tx"},
+		},
+		{
+			name:              "clone",
+			fileName:          "clone.go",
+			field:             "Size int|Tags []string|Groups map[string][]int|Reader io.Reader|Handler func()|Header http.Header|Labels Labels|Sets [2]Labels",
+			configType:        "Config",
+			configItemType:    "Item",
+			configBuilderType: "Builder",
+			configOptionType:  "Option",
+			needOption:        true,
+			args:              []string{"-clone", "-freeze"},
+		},
+		{
+			name:              "reset",
+			fileName:          "reset.go",
+			field:             "Size int|Name string|Tags []string",
+			configType:        "Config",
+			configItemType:    "Item",
+			configBuilderType: "Builder",
+			configOptionType:  "Option",
+			needOption:        true,
+			args:              []string{"-reset", "-freeze"},
+		},
+		{
+			name:              "values",
+			fileName:          "values.go",
+			field:             "Size int|Name string|Tags []string|Header http.Header",
+			configType:        "Config",
+			configItemType:    "Item",
+			configBuilderType: "Builder",
+			configOptionType:  "Option",
+			needOption:        true,
+			args:              []string{"-values"},
+		},
+		{
+			name:              "secret",
+			fileName:          "secret.go",
+			field:             "Size int|Password string @secret",
+			configType:        "Config",
+			configItemType:    "Item",
+			configBuilderType: "Builder",
+			configOptionType:  "Option",
+			needOption:        true,
+			args:              []string{"-values", "-clone"},
+		},
+		{
+			name:              "env",
+			fileName:          "env.go",
+			field:             "Size int|Timeout time.Duration|Rule Rule|Tags []string|DBPassword string @secret",
+			configType:        "Config",
+			configItemType:    "Item",
+			configBuilderType: "Builder",
+			configOptionType:  "Option",
+			args:              []string{"-env", "-envPrefix", "APP"},
+		},
+		{
+			name:              "dir",
+			fileName:          "dir.go",
+			field:             "Size int|Tags []string|DBPassword string @secret",
+			configType:        "Config",
+			configItemType:    "Item",
+			configBuilderType: "Builder",
+			configOptionType:  "Option",
+			args:              []string{"-dir"},
+		},
+		{
+			name:              "default",
+			fileName:          "default.go",
+			field:             `Size int @default=10 @doc="Size of the buffer."|Addr string @default="localhost:8080"|Timeout time.Duration @default=3*time.Second|Tags []string @default=[]string{"a", "b"}`,
+			configType:        "Config",
+			configItemType:    "Item",
+			configBuilderType: "Builder",
+			configOptionType:  "Option",
+			needOption:        true,
+		},
+		{
+			name:              "accessor",
+			fileName:          "accessor.go",
+			field:             "Size int|Addr string",
+			configType:        "Config",
+			configItemType:    "Item",
+			configBuilderType: "Builder",
+			configOptionType:  "Option",
+			needOption:        true,
+			args:              []string{"-accessor", "-clone"},
+		},
+		{
+			name:              "reader",
+			fileName:          "reader.go",
+			field:             "Size int|Addr string",
+			configType:        "Config",
+			configItemType:    "Item",
+			configBuilderType: "Builder",
+			configOptionType:  "Option",
+			args:              []string{"-accessor", "-reader"},
+		},
+		{
+			name:              "override",
+			fileName:          "override.go",
+			field:             "Size int|Addr string",
+			configType:        "Config",
+			configItemType:    "Item",
+			configBuilderType: "Builder",
+			configOptionType:  "Option",
+			needOption:        true,
+			args:              []string{"-override"},
+		},
+		{
+			name:              "context",
+			fileName:          "context.go",
+			field:             "Level string|Tags []string",
+			configType:        "Config",
+			configItemType:    "Item",
+			configBuilderType: "Builder",
+			configOptionType:  "Option",
+			needOption:        true,
+			args:              []string{"-context"},
+		},
+		{
+			name:              "deprecation",
+			fileName:          "deprecation.go",
+			field:             `Size int @alias=OldSize|Length int @deprecated="use Size"|Password string @secret @alias=Pass`,
+			configType:        "Config",
+			configItemType:    "Item",
+			configBuilderType: "Builder",
+			configOptionType:  "Option",
+			needOption:        true,
+			args:              []string{"-env", "-envPrefix", "APP", "-dir"},
+		},
+		{
+			name:              "schemaVersion",
+			fileName:          "schema_version.go",
+			field:             `Size int64|Timeout time.Duration|Addr string @alias=Host`,
+			configType:        "Config",
+			configItemType:    "Item",
+			configBuilderType: "Builder",
+			configOptionType:  "Option",
+			args:              []string{"-schemaVersion", "3"},
+		},
 	}
 
 	testcases := append(simpleTestcases, compositeTestcases...)
@@ -182,6 +344,7 @@ func (s *goConfig) compileAndRun(
 	configOptionType string,
 	needOption bool,
 	typePrefix string,
+	extraArgs []string,
 ) {
 	t.Helper()
 
@@ -202,6 +365,7 @@ func (s *goConfig) compileAndRun(
 	if needOption {
 		args.args = append(args.args, "-option")
 	}
+	args.args = append(args.args, extraArgs...)
 	t.Logf("run: goconfig %s", strings.Join(args.args, " "))
 	if err := run(s.goConfig, args.args...); err != nil {
 		t.Fatal(err)
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// exampleFilenames returns the names of the example files next to the generated file,
+// e.g. config.example.json and .env.example for config.go.
+func exampleFilenames(goFilename string) (jsonFilename, envFilename string) {
+	jsonFilename = strings.TrimSuffix(goFilename, filepath.Ext(goFilename)) + ".example.json"
+	envFilename = filepath.Join(filepath.Dir(goFilename), ".env.example")
+	return
+}
+
+type configExample struct {
+	config        *config
+	schemaVersion int // 0 if the documents are not versioned
+	env           *configEnv
+	// loadEnv is true if LoadEnv is generated.
+	loadEnv bool
+}
+
+// value returns the JSON value of the default of the field.
+// It reports false if the field has no default or the default is not a literal.
+// The default of the secret field is never exported.
+func (s *configExample) value(f *configField) (any, bool) {
+	if f.isSecret() {
+		return nil, false
+	}
+	v, ok := f.defaultValue()
+	if !ok {
+		return nil, false
+	}
+	return goExprToJSON(v)
+}
+
+// json returns the example JSON that has all the fields with the default or zero values.
+// The secret fields and the fields whose defaults are not literals have the zero values.
+// The fields of func and chan types are not included, same as the schema.
+func (s *configExample) json() ([]byte, error) {
+	r := orderedObject{}
+	if s.schemaVersion > 0 {
+		r.set(schemaVersionKey, s.schemaVersion)
+	}
+	for _, f := range s.config.fields {
+		t, ok := typeSchema(f.typeExpr, s.config.types)
+		if !ok {
+			continue
+		}
+		if v, ok := s.value(f); ok {
+			r.set(f.fieldName, v)
+			continue
+		}
+		r.set(f.fieldName, zeroJSON(t))
+	}
+	return marshalIndent(r)
+}
+
+// zeroJSON returns the JSON value of the zero value of the type of the schema.
+func zeroJSON(v orderedObject) any {
+	switch schemaKind(v) {
+	case "boolean":
+		return false
+	case "integer", "number":
+		return json.Number("0")
+	case "string":
+		return ""
+	case "array":
+		return []any{}
+	case "object":
+		r := orderedObject{}
+		for _, m := range v {
+			if m.key != "properties" {
+				continue
+			}
+			for _, p := range m.value.(orderedObject) {
+				r.set(p.key, zeroJSON(p.value.(orderedObject)))
+			}
+		}
+		return r
+	}
+	for _, m := range v {
+		if m.key != "type" {
+			continue
+		}
+		// time.Duration is either a string or an integer
+		if xs, ok := m.value.([]string); ok && !slices.Contains(xs, "null") && slices.Contains(xs, "integer") {
+			return json.Number("0")
+		}
+	}
+	return nil
+}
+
+// dotenv returns the example .env that has all the fields with the doc comments and the default values.
+// The fields without the literal defaults are commented out.
+func (s *configExample) dotenv() string {
+	var b stringBuilder
+	var written bool
+	for _, f := range s.config.fields {
+		if _, ok := typeSchema(f.typeExpr, nil); !ok {
+			continue
+		}
+		if written {
+			b.write("")
+		}
+		written = true
+		name := s.env.envName(f)
+		if d := f.doc(); d != "" {
+			for _, x := range strings.Split(d, "\n") {
+				b.writef("# %s", x)
+			}
+		}
+		if v, ok := f.deprecated(); ok {
+			b.writef("# deprecated: %s", v)
+		}
+		b.writef("# type: %s", f.typeName)
+		if f.isSecret() {
+			if s.loadEnv {
+				b.writef("# secret; %s_FILE can be set instead", name)
+			} else {
+				b.write("# secret")
+			}
+		}
+		// commented out since LoadEnv reads the empty value as set
+		v, ok := f.defaultValue()
+		if !ok || f.isSecret() {
+			b.writef("# %s=", name)
+			continue
+		}
+		x, ok := s.value(f)
+		if !ok {
+			b.writef("# default: %s", v)
+			b.writef("# %s=", name)
+			continue
+		}
+		b.writef("%s=%s", name, dotenvValue(x))
+	}
+	return b.String()
+}
+
+// dotenvValue returns the text of the JSON value, the form the generated LoadEnv reads.
+func dotenvValue(v any) string {
+	var x string
+	if s, ok := v.(string); ok {
+		x = s
+	} else {
+		b, _ := json.Marshal(v)
+		x = string(b)
+	}
+	if !strings.ContainsAny(x, " \t\r\n\"'#$\\`") {
+		return x
+	}
+	return strconv.Quote(x)
+}
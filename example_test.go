@@ -0,0 +1,74 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExample(t *testing.T) {
+	g := newGenerator(
+		`Size uint @default=10 @doc="Size of the buffer."|Name string @default="a b"|Tags []string @default=[]string{"x"}|Timeout time.Duration @default=3*time.Second|Handler func()|Password string @secret @default="x"|Limits map[string]int`,
+		"Config",
+		"Item",
+		"Builder",
+		"Option",
+		generatorOptions{
+			env:       true,
+			envPrefix: "APP",
+		},
+	)
+	e := &configExample{
+		config:  g.conf,
+		env:     g.env,
+		loadEnv: true,
+	}
+
+	t.Run("json", func(t *testing.T) {
+		got, err := e.json()
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, `{
+  "Size": 10,
+  "Name": "a b",
+  "Tags": [
+    "x"
+  ],
+  "Timeout": 0,
+  "Password": "",
+  "Limits": {}
+}
+`, string(got))
+	})
+
+	t.Run("dotenv", func(t *testing.T) {
+		assert.Equal(t, `# Size of the buffer.
+# type: uint
+APP_SIZE=10
+
+# type: string
+APP_NAME="a b"
+
+# type: []string
+APP_TAGS="[\"x\"]"
+
+# type: time.Duration
+# default: 3*time.Second
+# APP_TIMEOUT=
+
+# type: string
+# secret; APP_PASSWORD_FILE can be set instead
+# APP_PASSWORD=
+
+# type: map[string]int
+# APP_LIMITS=
+`, e.dotenv())
+	})
+}
+
+func TestExampleFilenames(t *testing.T) {
+	j, e := exampleFilenames("pkg/config.go")
+	assert.Equal(t, "pkg/config.example.json", j)
+	assert.Equal(t, "pkg/.env.example", e)
+}
@@ -16,7 +16,7 @@ type testcase struct {
 	configItemType    string
 	configBuilderType string
 	configOptionType  string
-	needOption        bool
+	options           generatorOptions
 	want              string
 }
 
@@ -27,9 +27,9 @@ func (tc testcase) test(t *testing.T) {
 		tc.configItemType,
 		tc.configBuilderType,
 		tc.configOptionType,
-		tc.needOption,
+		tc.options,
 	)
-	g.generate()
+	assert.Nil(t, g.generate())
 	got, err := format.Source(g.bytes())
 	assert.Nil(t, err)
 	w, err := format.Source([]byte(tc.want))
@@ -368,6 +368,2405 @@ func (s *Builder) Build() *Config {
 }
 
 func NewBuilder() *Builder { return &Builder{} }
+`,
+		},
+		{
+			name:              "freeze",
+			typeName:          "I int",
+			configType:        "Config",
+			configItemType:    "Item",
+			configBuilderType: "Builder",
+			configOptionType:  "Option",
+			options: generatorOptions{
+				option: true,
+				freeze: true,
+			},
+			want: `type Item[T any] struct {
+	modified     bool
+	value        T
+	defaultValue T
+	frozen       bool
+}
+
+func (s *Item[T]) Set(value T) {
+	if err := s.TrySet(value); err != nil {
+		panic(err)
+	}
+}
+
+// TrySet is like Set but returns ErrConfigFrozen instead of panicking when the item is frozen.
+func (s *Item[T]) TrySet(value T) error {
+	if s.frozen {
+		return ErrConfigFrozen
+	}
+	s.modified = true
+	s.value = value
+	return nil
+}
+func (s *Item[T]) Get() T {
+	if s.modified {
+		return s.value
+	}
+	return s.defaultValue
+}
+func (s *Item[T]) Default() T {
+	return s.defaultValue
+}
+func (s *Item[T]) IsModified() bool {
+	return s.modified
+}
+func NewItem[T any](defaultValue T) *Item[T] {
+	return &Item[T]{
+		defaultValue: defaultValue,
+	}
+}
+
+type Config struct {
+	I             *Item[int]
+	unfreezeToken *ConfigUnfreezeToken
+}
+type Builder struct {
+	i int
+}
+
+func (s *Builder) I(v int) *Builder {
+	s.i = v
+	return s
+}
+func (s *Builder) Build() *Config {
+	return &Config{
+		I: NewItem(s.i),
+	}
+}
+
+func NewBuilder() *Builder { return &Builder{} }
+func (s *Config) Apply(opt ...Option) {
+	for _, x := range opt {
+		x(s)
+	}
+}
+
+type Option func(*Config)
+
+func WithI(v int) Option {
+	return func(c *Config) {
+		c.I.Set(v)
+	}
+}
+
+// ErrConfigFrozen is returned when a frozen Config is modified.
+var ErrConfigFrozen = errors.New("config is frozen")
+
+// ConfigUnfreezeToken allows the holder to unfreeze the Config frozen by Freeze.
+type ConfigUnfreezeToken struct {
+	c *Config
+}
+
+// Freeze makes the config read-only.
+// After that, Set of the items and Apply panic, TrySet and TryApply return ErrConfigFrozen.
+// Freeze returns the token to unfreeze the config, or nil if the config is already frozen.
+func (s *Config) Freeze() *ConfigUnfreezeToken {
+	if s.unfreezeToken != nil {
+		return nil
+	}
+	s.unfreezeToken = &ConfigUnfreezeToken{c: s}
+	s.setFrozen(true)
+	return s.unfreezeToken
+}
+func (s *Config) IsFrozen() bool {
+	return s.unfreezeToken != nil
+}
+
+// Unfreeze makes the config writable again.
+// Unfreeze reports false if the token is no longer valid.
+func (t *ConfigUnfreezeToken) Unfreeze() bool {
+	if t == nil || t.c.unfreezeToken != t {
+		return false
+	}
+	t.c.unfreezeToken = nil
+	t.c.setFrozen(false)
+	return true
+}
+func (s *Config) setFrozen(v bool) {
+	s.I.frozen = v
+}
+
+// TryApply is like Apply but returns ErrConfigFrozen instead of panicking when the config is frozen.
+func (s *Config) TryApply(opt ...Option) error {
+	if s.IsFrozen() {
+		return ErrConfigFrozen
+	}
+	s.Apply(opt...)
+	return nil
+}
+`,
+		},
+		{
+			name:              "tx",
+			typeName:          "I int",
+			configType:        "Config",
+			configItemType:    "Item",
+			configBuilderType: "Builder",
+			configOptionType:  "Option",
+			options: generatorOptions{
+				option: true,
+				freeze: true,
+				tx:     true,
+			},
+			want: `type Item[T any] struct {
+	modified     bool
+	value        T
+	defaultValue T
+	frozen       bool
+}
+
+func (s *Item[T]) Set(value T) {
+	if err := s.TrySet(value); err != nil {
+		panic(err)
+	}
+}
+
+// TrySet is like Set but returns ErrConfigFrozen instead of panicking when the item is frozen.
+func (s *Item[T]) TrySet(value T) error {
+	if s.frozen {
+		return ErrConfigFrozen
+	}
+	s.modified = true
+	s.value = value
+	return nil
+}
+func (s *Item[T]) Get() T {
+	if s.modified {
+		return s.value
+	}
+	return s.defaultValue
+}
+func (s *Item[T]) Default() T {
+	return s.defaultValue
+}
+func (s *Item[T]) IsModified() bool {
+	return s.modified
+}
+func NewItem[T any](defaultValue T) *Item[T] {
+	return &Item[T]{
+		defaultValue: defaultValue,
+	}
+}
+func (s *Item[T]) clone(copyValue func(T) T) *Item[T] {
+	x := *s
+	x.frozen = false
+	if copyValue != nil {
+		x.value = copyValue(s.value)
+		x.defaultValue = copyValue(s.defaultValue)
+	}
+	return &x
+}
+
+func (s *Item[T]) equal(other *Item[T]) bool {
+	return s.modified == other.modified &&
+		s.equalValue(s.value, other.value) &&
+		s.equalValue(s.defaultValue, other.defaultValue)
+}
+
+// equalValue reports whether a and b are deeply equal.
+// Funcs are not comparable, they are equal if both are nil or both are not nil.
+func (s *Item[T]) equalValue(a, b T) bool {
+	if x, y := reflect.ValueOf(&a).Elem(), reflect.ValueOf(&b).Elem(); x.Kind() == reflect.Func {
+		return x.IsNil() == y.IsNil()
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+type Config struct {
+	I             *Item[int]
+	unfreezeToken *ConfigUnfreezeToken
+}
+type Builder struct {
+	i int
+}
+
+func (s *Builder) I(v int) *Builder {
+	s.i = v
+	return s
+}
+func (s *Builder) Build() *Config {
+	return &Config{
+		I: NewItem(s.i),
+	}
+}
+
+func NewBuilder() *Builder { return &Builder{} }
+func (s *Config) Apply(opt ...Option) {
+	for _, x := range opt {
+		x(s)
+	}
+}
+
+type Option func(*Config)
+
+func WithI(v int) Option {
+	return func(c *Config) {
+		c.I.Set(v)
+	}
+}
+
+// ErrConfigFrozen is returned when a frozen Config is modified.
+var ErrConfigFrozen = errors.New("config is frozen")
+
+// ConfigUnfreezeToken allows the holder to unfreeze the Config frozen by Freeze.
+type ConfigUnfreezeToken struct {
+	c *Config
+}
+
+// Freeze makes the config read-only.
+// After that, Set of the items and Apply panic, TrySet and TryApply return ErrConfigFrozen.
+// Freeze returns the token to unfreeze the config, or nil if the config is already frozen.
+func (s *Config) Freeze() *ConfigUnfreezeToken {
+	if s.unfreezeToken != nil {
+		return nil
+	}
+	s.unfreezeToken = &ConfigUnfreezeToken{c: s}
+	s.setFrozen(true)
+	return s.unfreezeToken
+}
+func (s *Config) IsFrozen() bool {
+	return s.unfreezeToken != nil
+}
+
+// Unfreeze makes the config writable again.
+// Unfreeze reports false if the token is no longer valid.
+func (t *ConfigUnfreezeToken) Unfreeze() bool {
+	if t == nil || t.c.unfreezeToken != t {
+		return false
+	}
+	t.c.unfreezeToken = nil
+	t.c.setFrozen(false)
+	return true
+}
+func (s *Config) setFrozen(v bool) {
+	s.I.frozen = v
+}
+
+// TryApply is like Apply but returns ErrConfigFrozen instead of panicking when the config is frozen.
+func (s *Config) TryApply(opt ...Option) error {
+	if s.IsFrozen() {
+		return ErrConfigFrozen
+	}
+	s.Apply(opt...)
+	return nil
+}
+func (s *Config) clone() *Config {
+	return &Config{
+		I: s.I.clone(nil),
+	}
+}
+
+// ApplyTx applies the options to a copy of the config and commits the copy all-or-nothing.
+// If the config has Validate() error method, the copy is committed only if it is valid.
+// ApplyTx returns the names of the changed fields.
+func (s *Config) ApplyTx(opt ...Option) ([]string, error) {
+	if s.IsFrozen() {
+		return nil, ErrConfigFrozen
+	}
+	staged := s.clone()
+	staged.Apply(opt...)
+	if v, ok := any(staged).(interface{ Validate() error }); ok {
+		if err := v.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	var changed []string
+	if !s.I.equal(staged.I) {
+		*s.I = *staged.I
+		changed = append(changed, "I")
+	}
+	return changed, nil
+}
+`,
+		},
+		{
+			name:              "clone",
+			typeName:          "I int|S []int|M map[string][]int",
+			configType:        "Config",
+			configItemType:    "Item",
+			configBuilderType: "Builder",
+			configOptionType:  "Option",
+			options: generatorOptions{
+				clone: true,
+			},
+			want: `type Item[T any] struct {
+	modified     bool
+	value        T
+	defaultValue T
+}
+
+func (s *Item[T]) Set(value T) {
+	s.modified = true
+	s.value = value
+}
+func (s *Item[T]) Get() T {
+	if s.modified {
+		return s.value
+	}
+	return s.defaultValue
+}
+func (s *Item[T]) Default() T {
+	return s.defaultValue
+}
+func (s *Item[T]) IsModified() bool {
+	return s.modified
+}
+func NewItem[T any](defaultValue T) *Item[T] {
+	return &Item[T]{
+		defaultValue: defaultValue,
+	}
+}
+func (s *Item[T]) clone(copyValue func(T) T) *Item[T] {
+	x := *s
+	if copyValue != nil {
+		x.value = copyValue(s.value)
+		x.defaultValue = copyValue(s.defaultValue)
+	}
+	return &x
+}
+
+func (s *Item[T]) equal(other *Item[T]) bool {
+	return s.modified == other.modified &&
+		s.equalValue(s.value, other.value) &&
+		s.equalValue(s.defaultValue, other.defaultValue)
+}
+
+// equalValue reports whether a and b are deeply equal.
+// Funcs are not comparable, they are equal if both are nil or both are not nil.
+func (s *Item[T]) equalValue(a, b T) bool {
+	if x, y := reflect.ValueOf(&a).Elem(), reflect.ValueOf(&b).Elem(); x.Kind() == reflect.Func {
+		return x.IsNil() == y.IsNil()
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+type Config struct {
+	I *Item[int]
+	S *Item[[]int]
+	M *Item[map[string][]int]
+}
+type Builder struct {
+	i int
+	s []int
+	m map[string][]int
+}
+
+func (s *Builder) I(v int) *Builder {
+	s.i = v
+	return s
+}
+func (s *Builder) S(v []int) *Builder {
+	s.s = v
+	return s
+}
+func (s *Builder) M(v map[string][]int) *Builder {
+	s.m = v
+	return s
+}
+func (s *Builder) Build() *Config {
+	return &Config{
+		I: NewItem(s.i),
+		S: NewItem(s.s),
+		M: NewItem(s.m),
+	}
+}
+
+func NewBuilder() *Builder { return &Builder{} }
+func (s *Config) clone() *Config {
+	return &Config{
+		I: s.I.clone(nil),
+		S: s.S.clone(func(v []int) (r []int) {
+			if v != nil {
+				r = make([]int, len(v))
+				copy(r, v)
+			}
+			return
+		}),
+		M: s.M.clone(func(v map[string][]int) (r map[string][]int) {
+			if v != nil {
+				r = make(map[string][]int, len(v))
+				for k1, v1 := range v {
+					var x1 []int
+					if v1 != nil {
+						x1 = make([]int, len(v1))
+						copy(x1, v1)
+					}
+					r[k1] = x1
+				}
+			}
+			return
+		}),
+	}
+}
+
+// Clone returns a deep copy of the config.
+// Slices and maps in the items are copied, the clone is not frozen.
+func (s *Config) Clone() *Config {
+	return s.clone()
+}
+
+// Equal reports whether all items of the configs have the same values, defaults and modified states.
+// Funcs are compared only by whether they are nil.
+func (s *Config) Equal(other *Config) bool {
+	return s.I.equal(other.I) &&
+		s.S.equal(other.S) &&
+		s.M.equal(other.M)
+}
+
+// ConfigFieldDiff is a difference of a field between configs.
+type ConfigFieldDiff struct {
+	Field       string
+	Old         any
+	New         any
+	OldModified bool
+	NewModified bool
+}
+
+// Diff returns the fields whose items are different from other.
+func (s *Config) Diff(other *Config) []ConfigFieldDiff {
+	var r []ConfigFieldDiff
+	if !s.I.equal(other.I) {
+		r = append(r, ConfigFieldDiff{
+			Field:       "I",
+			Old:         s.I.Get(),
+			New:         other.I.Get(),
+			OldModified: s.I.modified,
+			NewModified: other.I.modified,
+		})
+	}
+	if !s.S.equal(other.S) {
+		r = append(r, ConfigFieldDiff{
+			Field:       "S",
+			Old:         s.S.Get(),
+			New:         other.S.Get(),
+			OldModified: s.S.modified,
+			NewModified: other.S.modified,
+		})
+	}
+	if !s.M.equal(other.M) {
+		r = append(r, ConfigFieldDiff{
+			Field:       "M",
+			Old:         s.M.Get(),
+			New:         other.M.Get(),
+			OldModified: s.M.modified,
+			NewModified: other.M.modified,
+		})
+	}
+	return r
+}
+
+`,
+		},
+		{
+			name:              "reset",
+			typeName:          "I int|S []int",
+			configType:        "Config",
+			configItemType:    "Item",
+			configBuilderType: "Builder",
+			configOptionType:  "Option",
+			options: generatorOptions{
+				reset: true,
+			},
+			want: `type Item[T any] struct {
+	modified     bool
+	value        T
+	defaultValue T
+}
+
+func (s *Item[T]) Set(value T) {
+	s.modified = true
+	s.value = value
+}
+func (s *Item[T]) Get() T {
+	if s.modified {
+		return s.value
+	}
+	return s.defaultValue
+}
+func (s *Item[T]) Default() T {
+	return s.defaultValue
+}
+func (s *Item[T]) IsModified() bool {
+	return s.modified
+}
+func NewItem[T any](defaultValue T) *Item[T] {
+	return &Item[T]{
+		defaultValue: defaultValue,
+	}
+}
+
+// Reset discards the value set by Set, Get returns the default value again.
+func (s *Item[T]) Reset() {
+	var zero T
+	s.modified = false
+	s.value = zero
+}
+
+type Config struct {
+	I *Item[int]
+	S *Item[[]int]
+}
+type Builder struct {
+	i int
+	s []int
+}
+
+func (s *Builder) I(v int) *Builder {
+	s.i = v
+	return s
+}
+func (s *Builder) S(v []int) *Builder {
+	s.s = v
+	return s
+}
+func (s *Builder) Build() *Config {
+	return &Config{
+		I: NewItem(s.i),
+		S: NewItem(s.s),
+	}
+}
+
+func NewBuilder() *Builder { return &Builder{} }
+
+// ResetAll resets all items of the config.
+func (s *Config) ResetAll() {
+	s.I.Reset()
+	s.S.Reset()
+}
+
+// Reset resets the items of the fields.
+// Reset returns an error without resetting any items if unknown field is given.
+func (s *Config) Reset(fieldName ...string) error {
+	for _, x := range fieldName {
+		switch x {
+		case "I", "S":
+		default:
+			return fmt.Errorf("unknown field: %s", x)
+		}
+	}
+	for _, x := range fieldName {
+		switch x {
+		case "I":
+			s.I.Reset()
+		case "S":
+			s.S.Reset()
+		}
+	}
+	return nil
+}
+
+// ModifiedFields returns the names of the fields whose items are modified.
+func (s *Config) ModifiedFields() []string {
+	var r []string
+	if s.I.IsModified() {
+		r = append(r, "I")
+	}
+	if s.S.IsModified() {
+		r = append(r, "S")
+	}
+	return r
+}
+`,
+		},
+		{
+			name:              "values",
+			typeName:          "I int|S []int",
+			configType:        "Config",
+			configItemType:    "Item",
+			configBuilderType: "Builder",
+			configOptionType:  "Option",
+			options: generatorOptions{
+				option: true,
+				values: true,
+			},
+			want: `type Item[T any] struct {
+	modified     bool
+	value        T
+	defaultValue T
+}
+
+func (s *Item[T]) Set(value T) {
+	s.modified = true
+	s.value = value
+}
+func (s *Item[T]) Get() T {
+	if s.modified {
+		return s.value
+	}
+	return s.defaultValue
+}
+func (s *Item[T]) Default() T {
+	return s.defaultValue
+}
+func (s *Item[T]) IsModified() bool {
+	return s.modified
+}
+func NewItem[T any](defaultValue T) *Item[T] {
+	return &Item[T]{
+		defaultValue: defaultValue,
+	}
+}
+
+type Config struct {
+	I *Item[int]
+	S *Item[[]int]
+}
+type Builder struct {
+	i int
+	s []int
+}
+
+func (s *Builder) I(v int) *Builder {
+	s.i = v
+	return s
+}
+func (s *Builder) S(v []int) *Builder {
+	s.s = v
+	return s
+}
+func (s *Builder) Build() *Config {
+	return &Config{
+		I: NewItem(s.i),
+		S: NewItem(s.s),
+	}
+}
+
+func NewBuilder() *Builder { return &Builder{} }
+func (s *Config) Apply(opt ...Option) {
+	for _, x := range opt {
+		x(s)
+	}
+}
+
+type Option func(*Config)
+
+func WithI(v int) Option {
+	return func(c *Config) {
+		c.I.Set(v)
+	}
+}
+func WithS(v []int) Option {
+	return func(c *Config) {
+		c.S.Set(v)
+	}
+}
+
+// ToBuilder returns a builder seeded with the defaults of the config.
+func (s *Config) ToBuilder() *Builder {
+	return &Builder{
+		i: s.I.Default(),
+		s: s.S.Default(),
+	}
+}
+
+// ConfigValues is a snapshot of the values of Config.
+type ConfigValues struct {
+	I int
+	S []int
+}
+
+// Values returns the current values of the config.
+// Slices and maps in the values are copied.
+func (s *Config) Values() ConfigValues {
+	var r ConfigValues
+	r.I = s.I.Get()
+	{
+		v := s.S.Get()
+		if v != nil {
+			r.S = make([]int, len(v))
+			copy(r.S, v)
+		}
+	}
+	return r
+}
+
+// Apply sets all values to the config, all items of the config become modified.
+func (v ConfigValues) Apply(c *Config) {
+	c.I.Set(v.I)
+	c.S.Set(v.S)
+}
+
+// ToOptions returns the options to set all values.
+func (v ConfigValues) ToOptions() []Option {
+	return []Option{
+		WithI(v.I),
+		WithS(v.S),
+	}
+}
+`,
+		},
+		{
+			name:              "secret",
+			typeName:          "I int|P string @secret",
+			configType:        "Config",
+			configItemType:    "Item",
+			configBuilderType: "Builder",
+			configOptionType:  "Option",
+			options: generatorOptions{
+				option: true,
+				values: true,
+			},
+			want: `type Item[T any] struct {
+	modified     bool
+	value        T
+	defaultValue T
+	secret       bool
+}
+
+func (s *Item[T]) Set(value T) {
+	s.modified = true
+	s.value = value
+}
+func (s *Item[T]) Get() T {
+	if s.modified {
+		return s.value
+	}
+	return s.defaultValue
+}
+func (s *Item[T]) Default() T {
+	return s.defaultValue
+}
+func (s *Item[T]) IsModified() bool {
+	return s.modified
+}
+func NewItem[T any](defaultValue T) *Item[T] {
+	return &Item[T]{
+		defaultValue: defaultValue,
+	}
+}
+func (s *Item[T]) markSecret() *Item[T] {
+	s.secret = true
+	return s
+}
+
+// IsSecret reports whether the value should not be shown.
+func (s *Item[T]) IsSecret() bool {
+	return s.secret
+}
+func (s *Item[T]) String() string {
+	if s.secret {
+		return "[REDACTED]"
+	}
+	return fmt.Sprint(s.Get())
+}
+func (s *Item[T]) GoString() string {
+	if s.secret {
+		return "[REDACTED]"
+	}
+	return fmt.Sprintf("&%T{modified:%t, value:%#v, defaultValue:%#v}", *s, s.modified, s.value, s.defaultValue)
+}
+func (s *Item[T]) LogValue() slog.Value {
+	if s.secret {
+		return slog.StringValue("[REDACTED]")
+	}
+	return slog.AnyValue(s.Get())
+}
+func (s *Item[T]) MarshalJSON() ([]byte, error) {
+	if s.secret {
+		return json.Marshal("[REDACTED]")
+	}
+	return json.Marshal(s.Get())
+}
+
+type Config struct {
+	I *Item[int]
+	P *Item[string]
+}
+type Builder struct {
+	i int
+	p string
+}
+
+func (s *Builder) I(v int) *Builder {
+	s.i = v
+	return s
+}
+func (s *Builder) P(v string) *Builder {
+	s.p = v
+	return s
+}
+func (s *Builder) Build() *Config {
+	return &Config{
+		I: NewItem(s.i),
+		P: NewItem(s.p).markSecret(),
+	}
+}
+
+func NewBuilder() *Builder { return &Builder{} }
+func (s *Config) Apply(opt ...Option) {
+	for _, x := range opt {
+		x(s)
+	}
+}
+
+type Option func(*Config)
+
+func WithI(v int) Option {
+	return func(c *Config) {
+		c.I.Set(v)
+	}
+}
+func WithP(v string) Option {
+	return func(c *Config) {
+		c.P.Set(v)
+	}
+}
+
+// ToBuilder returns a builder seeded with the defaults of the config.
+func (s *Config) ToBuilder() *Builder {
+	return &Builder{
+		i: s.I.Default(),
+		p: s.P.Default(),
+	}
+}
+
+// ConfigValues is a snapshot of the values of Config.
+type ConfigValues struct {
+	I int
+	P string
+}
+
+// Values returns the current values of the config.
+// Slices and maps in the values are copied.
+func (s *Config) Values() ConfigValues {
+	var r ConfigValues
+	r.I = s.I.Get()
+	r.P = s.P.Get()
+	return r
+}
+
+func (v ConfigValues) String() string {
+	return fmt.Sprintf("{I:%v P:[REDACTED]}", v.I)
+}
+func (v ConfigValues) GoString() string {
+	return fmt.Sprintf("%T{I:%#v, P:[REDACTED]}", v, v.I)
+}
+func (v ConfigValues) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Any("I", v.I),
+		slog.String("P", "[REDACTED]"),
+	)
+}
+
+// Apply sets all values to the config, all items of the config become modified.
+func (v ConfigValues) Apply(c *Config) {
+	c.I.Set(v.I)
+	c.P.Set(v.P)
+}
+
+// ToOptions returns the options to set all values.
+func (v ConfigValues) ToOptions() []Option {
+	return []Option{
+		WithI(v.I),
+		WithP(v.P),
+	}
+}
+`,
+		},
+		{
+			name:              "env",
+			typeName:          "Size int|DBPassword string @secret",
+			configType:        "Config",
+			configItemType:    "Item",
+			configBuilderType: "Builder",
+			configOptionType:  "Option",
+			options: generatorOptions{
+				env:       true,
+				envPrefix: "APP",
+			},
+			want: `type Item[T any] struct {
+	modified     bool
+	value        T
+	defaultValue T
+	secret       bool
+}
+
+func (s *Item[T]) Set(value T) {
+	s.modified = true
+	s.value = value
+}
+func (s *Item[T]) Get() T {
+	if s.modified {
+		return s.value
+	}
+	return s.defaultValue
+}
+func (s *Item[T]) Default() T {
+	return s.defaultValue
+}
+func (s *Item[T]) IsModified() bool {
+	return s.modified
+}
+func NewItem[T any](defaultValue T) *Item[T] {
+	return &Item[T]{
+		defaultValue: defaultValue,
+	}
+}
+func (s *Item[T]) markSecret() *Item[T] {
+	s.secret = true
+	return s
+}
+
+// IsSecret reports whether the value should not be shown.
+func (s *Item[T]) IsSecret() bool {
+	return s.secret
+}
+func (s *Item[T]) String() string {
+	if s.secret {
+		return "[REDACTED]"
+	}
+	return fmt.Sprint(s.Get())
+}
+func (s *Item[T]) GoString() string {
+	if s.secret {
+		return "[REDACTED]"
+	}
+	return fmt.Sprintf("&%T{modified:%t, value:%#v, defaultValue:%#v}", *s, s.modified, s.value, s.defaultValue)
+}
+func (s *Item[T]) LogValue() slog.Value {
+	if s.secret {
+		return slog.StringValue("[REDACTED]")
+	}
+	return slog.AnyValue(s.Get())
+}
+func (s *Item[T]) MarshalJSON() ([]byte, error) {
+	if s.secret {
+		return json.Marshal("[REDACTED]")
+	}
+	return json.Marshal(s.Get())
+}
+
+// setText parses v as T and sets it.
+// v is used as it is for string kinds, parsed by UnmarshalText for encoding.TextUnmarshaler,
+// by time.ParseDuration for time.Duration and by json.Unmarshal for others.
+func (s *Item[T]) setText(v string) error {
+	var x T
+	switch p := any(&x).(type) {
+	case *time.Duration:
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return err
+		}
+		*p = d
+	case encoding.TextUnmarshaler:
+		if err := p.UnmarshalText([]byte(v)); err != nil {
+			return err
+		}
+	default:
+		if r := reflect.ValueOf(p).Elem(); r.Kind() == reflect.String {
+			r.SetString(v)
+		} else if err := json.Unmarshal([]byte(v), p); err != nil {
+			return err
+		}
+	}
+	s.Set(x)
+	return nil
+}
+
+// loadText sets the value from the source name.
+// The error does not contain the value if the item is secret.
+func (s *Item[T]) loadText(name, v string) error {
+	if err := s.setText(v); err != nil {
+		if s.secret {
+			return fmt.Errorf("%s: invalid value", name)
+		}
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+type Config struct {
+	Size       *Item[int]
+	DBPassword *Item[string]
+}
+type Builder struct {
+	size       int
+	dBPassword string
+}
+
+func (s *Builder) Size(v int) *Builder {
+	s.size = v
+	return s
+}
+func (s *Builder) DBPassword(v string) *Builder {
+	s.dBPassword = v
+	return s
+}
+func (s *Builder) Build() *Config {
+	return &Config{
+		Size:       NewItem(s.size),
+		DBPassword: NewItem(s.dBPassword).markSecret(),
+	}
+}
+
+func NewBuilder() *Builder { return &Builder{} }
+
+// LoadEnv sets the values of the fields from the environment variables.
+// The unset variables are ignored.
+// The values of the secret fields are also read from the files given by NAME_FILE variables,
+// whose trailing newlines are trimmed. The world-readable files are refused.
+func (s *Config) LoadEnv() error {
+	if v, ok := os.LookupEnv("APP_SIZE"); ok {
+		if err := s.Size.loadText("APP_SIZE", v); err != nil {
+			return err
+		}
+	}
+	if err := s.DBPassword.loadEnvFile("APP_DB_PASSWORD", "APP_DB_PASSWORD_FILE"); err != nil {
+		return err
+	}
+	return nil
+}
+
+// loadEnvFile sets the value from the environment variable name or the file given by fileName variable.
+func (s *Item[T]) loadEnvFile(name, fileName string) error {
+	v, ok := os.LookupEnv(name)
+	path, fileOk := os.LookupEnv(fileName)
+	switch {
+	case ok && fileOk:
+		return fmt.Errorf("both %s and %s are set", name, fileName)
+	case ok:
+		return s.loadText(name, v)
+	case fileOk:
+		info, err := os.Stat(path)
+		if err != nil {
+			return fmt.Errorf("%s: %w", fileName, err)
+		}
+		if info.Mode().Perm()&0o004 != 0 {
+			return fmt.Errorf("%s: %s is world-readable", fileName, path)
+		}
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("%s: %w", fileName, err)
+		}
+		return s.loadText(fileName, strings.TrimRight(string(b), "\r\n"))
+	default:
+		return nil
+	}
+}
+`,
+		},
+		{
+			name:              "dir",
+			typeName:          "Size int|DBPassword string @secret",
+			configType:        "Config",
+			configItemType:    "Item",
+			configBuilderType: "Builder",
+			configOptionType:  "Option",
+			options: generatorOptions{
+				dir: true,
+			},
+			want: `type Item[T any] struct {
+	modified     bool
+	value        T
+	defaultValue T
+	secret       bool
+}
+
+func (s *Item[T]) Set(value T) {
+	s.modified = true
+	s.value = value
+}
+func (s *Item[T]) Get() T {
+	if s.modified {
+		return s.value
+	}
+	return s.defaultValue
+}
+func (s *Item[T]) Default() T {
+	return s.defaultValue
+}
+func (s *Item[T]) IsModified() bool {
+	return s.modified
+}
+func NewItem[T any](defaultValue T) *Item[T] {
+	return &Item[T]{
+		defaultValue: defaultValue,
+	}
+}
+func (s *Item[T]) markSecret() *Item[T] {
+	s.secret = true
+	return s
+}
+
+// IsSecret reports whether the value should not be shown.
+func (s *Item[T]) IsSecret() bool {
+	return s.secret
+}
+func (s *Item[T]) String() string {
+	if s.secret {
+		return "[REDACTED]"
+	}
+	return fmt.Sprint(s.Get())
+}
+func (s *Item[T]) GoString() string {
+	if s.secret {
+		return "[REDACTED]"
+	}
+	return fmt.Sprintf("&%T{modified:%t, value:%#v, defaultValue:%#v}", *s, s.modified, s.value, s.defaultValue)
+}
+func (s *Item[T]) LogValue() slog.Value {
+	if s.secret {
+		return slog.StringValue("[REDACTED]")
+	}
+	return slog.AnyValue(s.Get())
+}
+func (s *Item[T]) MarshalJSON() ([]byte, error) {
+	if s.secret {
+		return json.Marshal("[REDACTED]")
+	}
+	return json.Marshal(s.Get())
+}
+
+// setText parses v as T and sets it.
+// v is used as it is for string kinds, parsed by UnmarshalText for encoding.TextUnmarshaler,
+// by time.ParseDuration for time.Duration and by json.Unmarshal for others.
+func (s *Item[T]) setText(v string) error {
+	var x T
+	switch p := any(&x).(type) {
+	case *time.Duration:
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return err
+		}
+		*p = d
+	case encoding.TextUnmarshaler:
+		if err := p.UnmarshalText([]byte(v)); err != nil {
+			return err
+		}
+	default:
+		if r := reflect.ValueOf(p).Elem(); r.Kind() == reflect.String {
+			r.SetString(v)
+		} else if err := json.Unmarshal([]byte(v), p); err != nil {
+			return err
+		}
+	}
+	s.Set(x)
+	return nil
+}
+
+// loadText sets the value from the source name.
+// The error does not contain the value if the item is secret.
+func (s *Item[T]) loadText(name, v string) error {
+	if err := s.setText(v); err != nil {
+		if s.secret {
+			return fmt.Errorf("%s: invalid value", name)
+		}
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+type Config struct {
+	Size       *Item[int]
+	DBPassword *Item[string]
+}
+type Builder struct {
+	size       int
+	dBPassword string
+}
+
+func (s *Builder) Size(v int) *Builder {
+	s.size = v
+	return s
+}
+func (s *Builder) DBPassword(v string) *Builder {
+	s.dBPassword = v
+	return s
+}
+func (s *Builder) Build() *Config {
+	return &Config{
+		Size:       NewItem(s.size),
+		DBPassword: NewItem(s.dBPassword).markSecret(),
+	}
+}
+
+func NewBuilder() *Builder { return &Builder{} }
+
+// LoadDir sets the values of the fields from the files in dir, like a mounted ConfigMap.
+// The file names are the field names or the snake cases of them, case-insensitive, hyphens as underscores,
+// e.g. DBPassword, db_password or DB-PASSWORD. The contents are the values, whose trailing newlines are trimmed.
+// If dir has ..data, the files are read from the directory it points to, to see a consistent snapshot
+// while ..data is swapped. Hidden files and directories are ignored.
+// If strict, unknown files are errors, otherwise they are ignored.
+func (s *Config) LoadDir(dir string, strict bool) error {
+	if p, err := filepath.EvalSymlinks(filepath.Join(dir, "..data")); err == nil {
+		dir = p
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		name := e.Name()
+		if strings.HasPrefix(name, ".") {
+			continue
+		}
+		path := filepath.Join(dir, name)
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			continue
+		}
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		v := strings.TrimRight(string(b), "\r\n")
+		switch strings.ToLower(strings.ReplaceAll(name, "-", "_")) {
+		case "size":
+			err = s.Size.loadText(path, v)
+		case "dbpassword", "db_password":
+			err = s.DBPassword.loadText(path, v)
+		default:
+			if strict {
+				return fmt.Errorf("unknown file: %s", path)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+`,
+		},
+		{
+			name:              "default",
+			typeName:          `I int @default=10 @doc="I is an integer."|S string @default="s|t"`,
+			configType:        "Config",
+			configItemType:    "Item",
+			configBuilderType: "Builder",
+			configOptionType:  "Option",
+			options: generatorOptions{
+				option: true,
+			},
+			want: `type Item[T any] struct {
+	modified     bool
+	value        T
+	defaultValue T
+}
+
+func (s *Item[T]) Set(value T) {
+	s.modified = true
+	s.value = value
+}
+func (s *Item[T]) Get() T {
+	if s.modified {
+		return s.value
+	}
+	return s.defaultValue
+}
+func (s *Item[T]) Default() T {
+	return s.defaultValue
+}
+func (s *Item[T]) IsModified() bool {
+	return s.modified
+}
+func NewItem[T any](defaultValue T) *Item[T] {
+	return &Item[T]{
+		defaultValue: defaultValue,
+	}
+}
+
+type Config struct {
+	// I is an integer.
+	I *Item[int]
+	S *Item[string]
+}
+type Builder struct {
+	i int
+	s string
+}
+
+// I is an integer.
+func (s *Builder) I(v int) *Builder {
+	s.i = v
+	return s
+}
+func (s *Builder) S(v string) *Builder {
+	s.s = v
+	return s
+}
+func (s *Builder) Build() *Config {
+	return &Config{
+		I: NewItem(s.i),
+		S: NewItem(s.s),
+	}
+}
+
+func NewBuilder() *Builder {
+	return &Builder{
+		i: 10,
+		s: "s|t",
+	}
+}
+func (s *Config) Apply(opt ...Option) {
+	for _, x := range opt {
+		x(s)
+	}
+}
+
+type Option func(*Config)
+
+// I is an integer.
+func WithI(v int) Option {
+	return func(c *Config) {
+		c.I.Set(v)
+	}
+}
+func WithS(v string) Option {
+	return func(c *Config) {
+		c.S.Set(v)
+	}
+}
+`,
+		},
+		{
+			name:              "accessor",
+			typeName:          `I int @doc="I is an integer."|S []string`,
+			configType:        "Config",
+			configItemType:    "Item",
+			configBuilderType: "Builder",
+			configOptionType:  "Option",
+			options: generatorOptions{
+				option:   true,
+				values:   true,
+				accessor: true,
+			},
+			want: `type Item[T any] struct {
+	modified     bool
+	value        T
+	defaultValue T
+}
+
+func (s *Item[T]) Set(value T) {
+	s.modified = true
+	s.value = value
+}
+func (s *Item[T]) Get() T {
+	if s.modified {
+		return s.value
+	}
+	return s.defaultValue
+}
+func (s *Item[T]) Default() T {
+	return s.defaultValue
+}
+func (s *Item[T]) IsModified() bool {
+	return s.modified
+}
+func NewItem[T any](defaultValue T) *Item[T] {
+	return &Item[T]{
+		defaultValue: defaultValue,
+	}
+}
+
+type Config struct {
+	i *Item[int]
+	s *Item[[]string]
+}
+
+// I is an integer.
+func (s *Config) I() int {
+	if s == nil || s.i == nil {
+		var zero int
+		return zero
+	}
+	return s.i.Get()
+}
+func (s *Config) S() []string {
+	if s == nil || s.s == nil {
+		var zero []string
+		return zero
+	}
+	return s.s.Get()
+}
+
+type Builder struct {
+	i int
+	s []string
+}
+
+// I is an integer.
+func (s *Builder) I(v int) *Builder {
+	s.i = v
+	return s
+}
+func (s *Builder) S(v []string) *Builder {
+	s.s = v
+	return s
+}
+func (s *Builder) Build() *Config {
+	return &Config{
+		i: NewItem(s.i),
+		s: NewItem(s.s),
+	}
+}
+
+func NewBuilder() *Builder { return &Builder{} }
+func (s *Config) Apply(opt ...Option) {
+	for _, x := range opt {
+		x(s)
+	}
+}
+
+type Option func(*Config)
+
+// I is an integer.
+func WithI(v int) Option {
+	return func(c *Config) {
+		c.i.Set(v)
+	}
+}
+func WithS(v []string) Option {
+	return func(c *Config) {
+		c.s.Set(v)
+	}
+}
+
+// ToBuilder returns a builder seeded with the defaults of the config.
+func (s *Config) ToBuilder() *Builder {
+	return &Builder{
+		i: s.i.Default(),
+		s: s.s.Default(),
+	}
+}
+
+// ConfigValues is a snapshot of the values of Config.
+type ConfigValues struct {
+	I int
+	S []string
+}
+
+// Values returns the current values of the config.
+// Slices and maps in the values are copied.
+func (s *Config) Values() ConfigValues {
+	var r ConfigValues
+	r.I = s.i.Get()
+	{
+		v := s.s.Get()
+		if v != nil {
+			r.S = make([]string, len(v))
+			copy(r.S, v)
+		}
+	}
+	return r
+}
+
+// Apply sets all values to the config, all items of the config become modified.
+func (v ConfigValues) Apply(c *Config) {
+	c.i.Set(v.I)
+	c.s.Set(v.S)
+}
+
+// ToOptions returns the options to set all values.
+func (v ConfigValues) ToOptions() []Option {
+	return []Option{
+		WithI(v.I),
+		WithS(v.S),
+	}
+}
+`,
+		},
+		{
+			name:              "reader",
+			typeName:          `I int @doc="I is an integer."|S []string`,
+			configType:        "Config",
+			configItemType:    "Item",
+			configBuilderType: "Builder",
+			configOptionType:  "Option",
+			options: generatorOptions{
+				accessor: true,
+				reader:   true,
+			},
+			want: `type Item[T any] struct {
+	modified     bool
+	value        T
+	defaultValue T
+}
+
+func (s *Item[T]) Set(value T) {
+	s.modified = true
+	s.value = value
+}
+func (s *Item[T]) Get() T {
+	if s.modified {
+		return s.value
+	}
+	return s.defaultValue
+}
+func (s *Item[T]) Default() T {
+	return s.defaultValue
+}
+func (s *Item[T]) IsModified() bool {
+	return s.modified
+}
+func NewItem[T any](defaultValue T) *Item[T] {
+	return &Item[T]{
+		defaultValue: defaultValue,
+	}
+}
+
+type Config struct {
+	i *Item[int]
+	s *Item[[]string]
+}
+
+// I is an integer.
+func (s *Config) I() int {
+	if s == nil || s.i == nil {
+		var zero int
+		return zero
+	}
+	return s.i.Get()
+}
+func (s *Config) S() []string {
+	if s == nil || s.s == nil {
+		var zero []string
+		return zero
+	}
+	return s.s.Get()
+}
+
+type Builder struct {
+	i int
+	s []string
+}
+
+// I is an integer.
+func (s *Builder) I(v int) *Builder {
+	s.i = v
+	return s
+}
+func (s *Builder) S(v []string) *Builder {
+	s.s = v
+	return s
+}
+func (s *Builder) Build() *Config {
+	return &Config{
+		i: NewItem(s.i),
+		s: NewItem(s.s),
+	}
+}
+
+func NewBuilder() *Builder { return &Builder{} }
+
+// ConfigReader is the read-only view of Config.
+type ConfigReader interface {
+	// I is an integer.
+	I() int
+	S() []string
+}
+
+var _ ConfigReader = (*Config)(nil)
+
+// FakeConfig is a ConfigReader whose values are set directly, for tests.
+type FakeConfig struct {
+	IValue int
+	SValue []string
+}
+
+var _ ConfigReader = (*FakeConfig)(nil)
+
+func (s *FakeConfig) I() int      { return s.IValue }
+func (s *FakeConfig) S() []string { return s.SValue }
+`,
+		},
+		{
+			name:              "override",
+			typeName:          "I int|S []string",
+			configType:        "Config",
+			configItemType:    "Item",
+			configBuilderType: "Builder",
+			configOptionType:  "Option",
+			options: generatorOptions{
+				option:   true,
+				override: true,
+			},
+			want: `type Item[T any] struct {
+	modified     bool
+	value        T
+	defaultValue T
+}
+
+func (s *Item[T]) Set(value T) {
+	s.modified = true
+	s.value = value
+}
+func (s *Item[T]) Get() T {
+	if s.modified {
+		return s.value
+	}
+	return s.defaultValue
+}
+func (s *Item[T]) Default() T {
+	return s.defaultValue
+}
+func (s *Item[T]) IsModified() bool {
+	return s.modified
+}
+func NewItem[T any](defaultValue T) *Item[T] {
+	return &Item[T]{
+		defaultValue: defaultValue,
+	}
+}
+
+type Config struct {
+	I *Item[int]
+	S *Item[[]string]
+}
+type Builder struct {
+	i int
+	s []string
+}
+
+func (s *Builder) I(v int) *Builder {
+	s.i = v
+	return s
+}
+func (s *Builder) S(v []string) *Builder {
+	s.s = v
+	return s
+}
+func (s *Builder) Build() *Config {
+	return &Config{
+		I: NewItem(s.i),
+		S: NewItem(s.s),
+	}
+}
+
+func NewBuilder() *Builder { return &Builder{} }
+func (s *Config) Apply(opt ...Option) {
+	for _, x := range opt {
+		x(s)
+	}
+}
+
+type Option func(*Config)
+
+func WithI(v int) Option {
+	return func(c *Config) {
+		c.I.Set(v)
+	}
+}
+func WithS(v []string) Option {
+	return func(c *Config) {
+		c.S.Set(v)
+	}
+}
+
+// Override applies the options to the config and restores the items when the test finishes.
+// t is usually testing.TB.
+func (s *Config) Override(t interface {
+	Helper()
+	Cleanup(func())
+}, opt ...Option) {
+	t.Helper()
+	{
+		v := *s.I
+		t.Cleanup(func() { *s.I = v })
+	}
+	{
+		v := *s.S
+		t.Cleanup(func() { *s.S = v })
+	}
+	s.Apply(opt...)
+}
+`,
+		},
+		{
+			name:              "context",
+			typeName:          "I int",
+			configType:        "Config",
+			configItemType:    "Item",
+			configBuilderType: "Builder",
+			configOptionType:  "Option",
+			options: generatorOptions{
+				option:  true,
+				context: true,
+			},
+			want: `type Item[T any] struct {
+	modified     bool
+	value        T
+	defaultValue T
+}
+
+func (s *Item[T]) Set(value T) {
+	s.modified = true
+	s.value = value
+}
+func (s *Item[T]) Get() T {
+	if s.modified {
+		return s.value
+	}
+	return s.defaultValue
+}
+func (s *Item[T]) Default() T {
+	return s.defaultValue
+}
+func (s *Item[T]) IsModified() bool {
+	return s.modified
+}
+func NewItem[T any](defaultValue T) *Item[T] {
+	return &Item[T]{
+		defaultValue: defaultValue,
+	}
+}
+func (s *Item[T]) clone(copyValue func(T) T) *Item[T] {
+	x := *s
+	if copyValue != nil {
+		x.value = copyValue(s.value)
+		x.defaultValue = copyValue(s.defaultValue)
+	}
+	return &x
+}
+
+func (s *Item[T]) equal(other *Item[T]) bool {
+	return s.modified == other.modified &&
+		s.equalValue(s.value, other.value) &&
+		s.equalValue(s.defaultValue, other.defaultValue)
+}
+
+// equalValue reports whether a and b are deeply equal.
+// Funcs are not comparable, they are equal if both are nil or both are not nil.
+func (s *Item[T]) equalValue(a, b T) bool {
+	if x, y := reflect.ValueOf(&a).Elem(), reflect.ValueOf(&b).Elem(); x.Kind() == reflect.Func {
+		return x.IsNil() == y.IsNil()
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+type Config struct {
+	I *Item[int]
+}
+type Builder struct {
+	i int
+}
+
+func (s *Builder) I(v int) *Builder {
+	s.i = v
+	return s
+}
+func (s *Builder) Build() *Config {
+	return &Config{
+		I: NewItem(s.i),
+	}
+}
+
+func NewBuilder() *Builder { return &Builder{} }
+func (s *Config) Apply(opt ...Option) {
+	for _, x := range opt {
+		x(s)
+	}
+}
+
+type Option func(*Config)
+
+func WithI(v int) Option {
+	return func(c *Config) {
+		c.I.Set(v)
+	}
+}
+func (s *Config) clone() *Config {
+	return &Config{
+		I: s.I.clone(nil),
+	}
+}
+
+type configContextKey struct{}
+
+// NewConfigContext returns a copy of ctx that carries the config.
+func NewConfigContext(ctx context.Context, c *Config) context.Context {
+	return context.WithValue(ctx, configContextKey{}, c)
+}
+
+// ConfigFromContext returns the config carried by ctx.
+func ConfigFromContext(ctx context.Context) (*Config, bool) {
+	c, ok := ctx.Value(configContextKey{}).(*Config)
+	return c, ok && c != nil
+}
+
+// WithConfigOverrides returns a copy of ctx that carries a clone of the config of ctx with the options applied.
+// The config of ctx is not modified. ctx is returned as is if it does not carry the config.
+func WithConfigOverrides(ctx context.Context, opt ...Option) context.Context {
+	c, ok := ConfigFromContext(ctx)
+	if !ok {
+		return ctx
+	}
+	c = c.clone()
+	c.Apply(opt...)
+	return NewConfigContext(ctx, c)
+}
+`,
+		},
+		{
+			name:              "deprecation",
+			typeName:          `Size int @alias=OldSize @doc="Size of the buffer."|Length int @deprecated="use Size"`,
+			configType:        "Config",
+			configItemType:    "Item",
+			configBuilderType: "Builder",
+			configOptionType:  "Option",
+			options: generatorOptions{
+				option: true,
+				env:    true,
+				dir:    true,
+			},
+			want: `type Item[T any] struct {
+	modified     bool
+	value        T
+	defaultValue T
+}
+
+func (s *Item[T]) Set(value T) {
+	s.modified = true
+	s.value = value
+}
+func (s *Item[T]) Get() T {
+	if s.modified {
+		return s.value
+	}
+	return s.defaultValue
+}
+func (s *Item[T]) Default() T {
+	return s.defaultValue
+}
+func (s *Item[T]) IsModified() bool {
+	return s.modified
+}
+func NewItem[T any](defaultValue T) *Item[T] {
+	return &Item[T]{
+		defaultValue: defaultValue,
+	}
+}
+
+// setText parses v as T and sets it.
+// v is used as it is for string kinds, parsed by UnmarshalText for encoding.TextUnmarshaler,
+// by time.ParseDuration for time.Duration and by json.Unmarshal for others.
+func (s *Item[T]) setText(v string) error {
+	var x T
+	switch p := any(&x).(type) {
+	case *time.Duration:
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return err
+		}
+		*p = d
+	case encoding.TextUnmarshaler:
+		if err := p.UnmarshalText([]byte(v)); err != nil {
+			return err
+		}
+	default:
+		if r := reflect.ValueOf(p).Elem(); r.Kind() == reflect.String {
+			r.SetString(v)
+		} else if err := json.Unmarshal([]byte(v), p); err != nil {
+			return err
+		}
+	}
+	s.Set(x)
+	return nil
+}
+
+// loadText sets the value from the source name.
+// The error does not contain the value if the item is secret.
+func (s *Item[T]) loadText(name, v string) error {
+	if err := s.setText(v); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+type Config struct {
+	// Size of the buffer.
+	Size *Item[int]
+	// Deprecated: use Size
+	Length *Item[int]
+}
+type Builder struct {
+	size   int
+	length int
+}
+
+// Size of the buffer.
+func (s *Builder) Size(v int) *Builder {
+	s.size = v
+	return s
+}
+
+// Deprecated: use Size instead.
+func (s *Builder) OldSize(v int) *Builder { return s.Size(v) }
+
+// Deprecated: use Size
+func (s *Builder) Length(v int) *Builder {
+	s.length = v
+	return s
+}
+func (s *Builder) Build() *Config {
+	return &Config{
+		Size:   NewItem(s.size),
+		Length: NewItem(s.length),
+	}
+}
+
+func NewBuilder() *Builder { return &Builder{} }
+func (s *Config) Apply(opt ...Option) {
+	for _, x := range opt {
+		x(s)
+	}
+}
+
+type Option func(*Config)
+
+// Size of the buffer.
+func WithSize(v int) Option {
+	return func(c *Config) {
+		c.Size.Set(v)
+	}
+}
+
+// Deprecated: use WithSize instead.
+func WithOldSize(v int) Option { return WithSize(v) }
+
+// Deprecated: use Size
+func WithLength(v int) Option {
+	return func(c *Config) {
+		c.Length.Set(v)
+	}
+}
+
+// LoadEnv sets the values of the fields from the environment variables.
+// The unset variables are ignored.
+// The deprecated variables, including the aliases, are warned by the deprecation logger.
+// The aliases are read before the variables of the fields, so the latter win.
+func (s *Config) LoadEnv() error {
+	if os.Getenv("OLD_SIZE") != "" {
+		warnConfigDeprecation("OLD_SIZE is deprecated, use SIZE instead")
+	}
+	if v, ok := os.LookupEnv("OLD_SIZE"); ok {
+		if err := s.Size.loadText("OLD_SIZE", v); err != nil {
+			return err
+		}
+	}
+	if v, ok := os.LookupEnv("SIZE"); ok {
+		if err := s.Size.loadText("SIZE", v); err != nil {
+			return err
+		}
+	}
+	if os.Getenv("LENGTH") != "" {
+		warnConfigDeprecation("LENGTH is deprecated: use Size")
+	}
+	if v, ok := os.LookupEnv("LENGTH"); ok {
+		if err := s.Length.loadText("LENGTH", v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadDir sets the values of the fields from the files in dir, like a mounted ConfigMap.
+// The file names are the field names or the snake cases of them, case-insensitive, hyphens as underscores,
+// e.g. DBPassword, db_password or DB-PASSWORD. The contents are the values, whose trailing newlines are trimmed.
+// If dir has ..data, the files are read from the directory it points to, to see a consistent snapshot
+// while ..data is swapped. Hidden files and directories are ignored.
+// If strict, unknown files are errors, otherwise they are ignored.
+// The deprecated files, including the aliases, are warned by the deprecation logger.
+// The aliases are ignored if the files of the fields are given.
+func (s *Config) LoadDir(dir string, strict bool) error {
+	if p, err := filepath.EvalSymlinks(filepath.Join(dir, "..data")); err == nil {
+		dir = p
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	// the files of the fields win over the aliases
+	given := map[string]bool{}
+	for _, e := range entries {
+		if !e.IsDir() {
+			given[strings.ToLower(strings.ReplaceAll(e.Name(), "-", "_"))] = true
+		}
+	}
+	for _, e := range entries {
+		name := e.Name()
+		if strings.HasPrefix(name, ".") {
+			continue
+		}
+		path := filepath.Join(dir, name)
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			continue
+		}
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		v := strings.TrimRight(string(b), "\r\n")
+		switch strings.ToLower(strings.ReplaceAll(name, "-", "_")) {
+		case "oldsize", "old_size":
+			warnConfigDeprecation(fmt.Sprintf("file %s is deprecated, use Size instead", name))
+			if given["size"] {
+				continue
+			}
+			err = s.Size.loadText(path, v)
+		case "size":
+			err = s.Size.loadText(path, v)
+		case "length":
+			warnConfigDeprecation(fmt.Sprintf("file %s is deprecated: %s", name, "use Size"))
+			err = s.Length.loadText(path, v)
+		default:
+			if strict {
+				return fmt.Errorf("unknown file: %s", path)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ConfigDeprecationLogger logs the uses of the deprecated fields and aliases by the loaders.
+// Each message is logged once. Replace it to change the destination.
+var ConfigDeprecationLogger = func(msg string) { log.Print(msg) }
+
+var configDeprecationWarned sync.Map
+
+func warnConfigDeprecation(msg string) {
+	if _, loaded := configDeprecationWarned.LoadOrStore(msg, true); !loaded {
+		ConfigDeprecationLogger(msg)
+	}
+}
+`,
+		},
+		{
+			name:              "schemaVersion",
+			typeName:          "Size int|Timeout time.Duration|Password string @secret|Handler func()",
+			configType:        "Config",
+			configItemType:    "Item",
+			configBuilderType: "Builder",
+			configOptionType:  "Option",
+			options: generatorOptions{
+				schemaVersion: 2,
+			},
+			want: `type Item[T any] struct {
+	modified     bool
+	value        T
+	defaultValue T
+	secret       bool
+}
+
+func (s *Item[T]) Set(value T) {
+	s.modified = true
+	s.value = value
+}
+func (s *Item[T]) Get() T {
+	if s.modified {
+		return s.value
+	}
+	return s.defaultValue
+}
+func (s *Item[T]) Default() T {
+	return s.defaultValue
+}
+func (s *Item[T]) IsModified() bool {
+	return s.modified
+}
+func NewItem[T any](defaultValue T) *Item[T] {
+	return &Item[T]{
+		defaultValue: defaultValue,
+	}
+}
+func (s *Item[T]) clone(copyValue func(T) T) *Item[T] {
+	x := *s
+	if copyValue != nil {
+		x.value = copyValue(s.value)
+		x.defaultValue = copyValue(s.defaultValue)
+	}
+	return &x
+}
+
+func (s *Item[T]) markSecret() *Item[T] {
+	s.secret = true
+	return s
+}
+
+// IsSecret reports whether the value should not be shown.
+func (s *Item[T]) IsSecret() bool {
+	return s.secret
+}
+func (s *Item[T]) String() string {
+	if s.secret {
+		return "[REDACTED]"
+	}
+	return fmt.Sprint(s.Get())
+}
+func (s *Item[T]) GoString() string {
+	if s.secret {
+		return "[REDACTED]"
+	}
+	return fmt.Sprintf("&%T{modified:%t, value:%#v, defaultValue:%#v}", *s, s.modified, s.value, s.defaultValue)
+}
+func (s *Item[T]) LogValue() slog.Value {
+	if s.secret {
+		return slog.StringValue("[REDACTED]")
+	}
+	return slog.AnyValue(s.Get())
+}
+func (s *Item[T]) MarshalJSON() ([]byte, error) {
+	if s.secret {
+		return json.Marshal("[REDACTED]")
+	}
+	return json.Marshal(s.Get())
+}
+
+// setText parses v as T and sets it.
+// v is used as it is for string kinds, parsed by UnmarshalText for encoding.TextUnmarshaler,
+// by time.ParseDuration for time.Duration and by json.Unmarshal for others.
+func (s *Item[T]) setText(v string) error {
+	var x T
+	switch p := any(&x).(type) {
+	case *time.Duration:
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return err
+		}
+		*p = d
+	case encoding.TextUnmarshaler:
+		if err := p.UnmarshalText([]byte(v)); err != nil {
+			return err
+		}
+	default:
+		if r := reflect.ValueOf(p).Elem(); r.Kind() == reflect.String {
+			r.SetString(v)
+		} else if err := json.Unmarshal([]byte(v), p); err != nil {
+			return err
+		}
+	}
+	s.Set(x)
+	return nil
+}
+
+// loadText sets the value from the source name.
+// The error does not contain the value if the item is secret.
+func (s *Item[T]) loadText(name, v string) error {
+	if err := s.setText(v); err != nil {
+		if s.secret {
+			return fmt.Errorf("%s: invalid value", name)
+		}
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+// loadJSON sets the value from the JSON value of the source name.
+// time.Duration also accepts the string like "3s".
+// The error does not contain the value if the item is secret.
+func (s *Item[T]) loadJSON(name string, v []byte) error {
+	var x T
+	if _, ok := any(x).(time.Duration); ok {
+		var text string
+		if err := json.Unmarshal(v, &text); err == nil {
+			return s.loadText(name, text)
+		}
+	}
+	if err := json.Unmarshal(v, &x); err != nil {
+		if s.secret {
+			return fmt.Errorf("%s: invalid value", name)
+		}
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	s.Set(x)
+	return nil
+}
+
+// copyItemValue returns a deep copy of v, copying the slices, maps, arrays and structs in it.
+// Pointers, interfaces and the unexported fields of structs are shared.
+func copyItemValue[T any](v T) T {
+	var r T
+	copyItemReflect(reflect.ValueOf(&r).Elem(), reflect.ValueOf(&v).Elem())
+	return r
+}
+func copyItemReflect(dst, src reflect.Value) {
+	switch src.Kind() {
+	case reflect.Slice:
+		if src.IsNil() {
+			return
+		}
+		dst.Set(reflect.MakeSlice(src.Type(), src.Len(), src.Len()))
+		for i := 0; i < src.Len(); i++ {
+			copyItemReflect(dst.Index(i), src.Index(i))
+		}
+	case reflect.Array:
+		for i := 0; i < src.Len(); i++ {
+			copyItemReflect(dst.Index(i), src.Index(i))
+		}
+	case reflect.Map:
+		if src.IsNil() {
+			return
+		}
+		dst.Set(reflect.MakeMapWithSize(src.Type(), src.Len()))
+		for it := src.MapRange(); it.Next(); {
+			x := reflect.New(src.Type().Elem()).Elem()
+			copyItemReflect(x, it.Value())
+			dst.SetMapIndex(it.Key(), x)
+		}
+	case reflect.Struct:
+		dst.Set(src)
+		for i := 0; i < src.NumField(); i++ {
+			if dst.Field(i).CanSet() {
+				copyItemReflect(dst.Field(i), src.Field(i))
+			}
+		}
+	default:
+		dst.Set(src)
+	}
+}
+
+type Config struct {
+	Size     *Item[int]
+	Timeout  *Item[time.Duration]
+	Password *Item[string]
+	Handler  *Item[func()]
+}
+type Builder struct {
+	size     int
+	timeout  time.Duration
+	password string
+	handler  func()
+}
+
+func (s *Builder) Size(v int) *Builder {
+	s.size = v
+	return s
+}
+func (s *Builder) Timeout(v time.Duration) *Builder {
+	s.timeout = v
+	return s
+}
+func (s *Builder) Password(v string) *Builder {
+	s.password = v
+	return s
+}
+func (s *Builder) Handler(v func()) *Builder {
+	s.handler = v
+	return s
+}
+func (s *Builder) Build() *Config {
+	return &Config{
+		Size:     NewItem(s.size),
+		Timeout:  NewItem(s.timeout),
+		Password: NewItem(s.password).markSecret(),
+		Handler:  NewItem(s.handler),
+	}
+}
+
+func NewBuilder() *Builder { return &Builder{} }
+func (s *Config) clone() *Config {
+	return &Config{
+		Size:     s.Size.clone(nil),
+		Timeout:  s.Timeout.clone(copyItemValue[time.Duration]),
+		Password: s.Password.clone(nil),
+		Handler:  s.Handler.clone(nil),
+	}
+}
+
+// ConfigSchemaVersion is the version of the config documents read by LoadJSON.
+const ConfigSchemaVersion = 2
+
+var configMigrations = map[int]func(map[string]any) error{}
+
+// RegisterConfigMigration registers the migration of the config documents from the version to the next one.
+// The migration modifies the document in place, whose numbers are json.Number.
+func RegisterConfigMigration(from int, migrate func(map[string]any) error) {
+	configMigrations[from] = migrate
+}
+
+// LoadJSON sets the values of the fields from the JSON document.
+// The keys are the field names and "schemaVersion", the version of the document, 1 if not present.
+// The old documents are migrated by the registered migrations before the values are set,
+// the documents newer than ConfigSchemaVersion are errors. Unknown keys are errors.
+// The values are set to a copy of the config, which is committed only if all of them are loaded
+// and, if the config has Validate() error method, the copy is valid.
+func (s *Config) LoadJSON(data []byte) error {
+	var doc map[string]any
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	if err := d.Decode(&doc); err != nil {
+		return err
+	}
+	if doc == nil { // null
+		doc = map[string]any{}
+	}
+	version := 1
+	if v, ok := doc["schemaVersion"]; ok {
+		x, ok := v.(json.Number)
+		if !ok {
+			return fmt.Errorf("invalid schemaVersion: %v", v)
+		}
+		n, err := strconv.Atoi(x.String())
+		if err != nil {
+			return fmt.Errorf("invalid schemaVersion: %w", err)
+		}
+		version = n
+		delete(doc, "schemaVersion")
+	}
+	if version > ConfigSchemaVersion {
+		return fmt.Errorf("schemaVersion %d is newer than %d", version, ConfigSchemaVersion)
+	}
+	for ; version < ConfigSchemaVersion; version++ {
+		migrate, ok := configMigrations[version]
+		if !ok {
+			return fmt.Errorf("no migration from schemaVersion %d", version)
+		}
+		if err := migrate(doc); err != nil {
+			return fmt.Errorf("failed to migrate from schemaVersion %d: %w", version, err)
+		}
+	}
+	staged := s.clone()
+	keys := make([]string, 0, len(doc))
+	for k := range doc {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		v, err := json.Marshal(doc[k])
+		if err != nil {
+			return fmt.Errorf("%s: %w", k, err)
+		}
+		switch k {
+		case "Size":
+			err = staged.Size.loadJSON(k, v)
+		case "Timeout":
+			err = staged.Timeout.loadJSON(k, v)
+		case "Password":
+			err = staged.Password.loadJSON(k, v)
+		default:
+			return fmt.Errorf("unknown key: %s", k)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if v, ok := any(staged).(interface{ Validate() error }); ok {
+		if err := v.Validate(); err != nil {
+			return err
+		}
+	}
+	*s.Size = *staged.Size
+	*s.Timeout = *staged.Timeout
+	*s.Password = *staged.Password
+	*s.Handler = *staged.Handler
+	return nil
+}
 `,
 		},
 	}
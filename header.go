@@ -0,0 +1,279 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"go/build/constraint"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// fileHeader is the beginning of the generated files before the declarations.
+type fileHeader struct {
+	// buildTags is the expression of the //go:build line, empty if none.
+	buildTags string
+	// license is the comment before the generated code comment, empty if none.
+	license string
+	// args are the arguments of the command.
+	args    []string
+	pkgName string
+}
+
+// String returns the header like
+//
+//	//go:build linux
+//
+//	// Copyright ...
+//
+//	// Code generated by "goconfig -field 'Size int'"; DO NOT EDIT.
+//
+//	package example
+//
+// The //go:build line comes first because only line comments may precede it
+// and the license may be a block comment.
+func (s fileHeader) String() string {
+	var b strings.Builder
+	if s.buildTags != "" {
+		fmt.Fprintf(&b, "//go:build %s\n\n", s.buildTags)
+	}
+	if s.license != "" {
+		fmt.Fprintf(&b, "%s\n\n", s.license)
+	}
+	fmt.Fprintf(&b, "// Code generated by \"goconfig %s\"; DO NOT EDIT.\n\n", strings.Join(s.args, " "))
+	fmt.Fprintf(&b, "package %s\n\n", s.pkgName)
+	return b.String()
+}
+
+var generatedHeaderRegexp = regexp.MustCompile(`^// Code generated by "goconfig (.*)"; DO NOT EDIT\.$`)
+
+// readGeneratedArgs returns the arguments in the header of the file generated by goconfig.
+// It reports false if the file is not generated by goconfig.
+func readGeneratedArgs(fileName string) (string, bool, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return "", false, err
+	}
+	defer f.Close()
+	// the header follows the //go:build line and the license, if any
+	r := bufio.NewScanner(f)
+	for r.Scan() {
+		line := r.Text()
+		if strings.HasPrefix(line, "package ") {
+			break
+		}
+		if m := generatedHeaderRegexp.FindStringSubmatch(line); m != nil {
+			return m[1], true, nil
+		}
+	}
+	return "", false, r.Err()
+}
+
+// parseHeaderFlags parses the command line recorded in the header by the flags of gen.
+func parseHeaderFlags(line string) (*flag.FlagSet, *specFlags, *genFlags, error) {
+	words, err := shellSplit(line)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("invalid command line: %w", err)
+	}
+	fs := flag.NewFlagSet("header", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	var (
+		spec = newSpecFlags(fs)
+		gen  = newGenFlags(fs)
+	)
+	if err := fs.Parse(words); err != nil {
+		return nil, nil, nil, err
+	}
+	return fs, spec, gen, nil
+}
+
+// readSplitDest returns the output of the command recorded in the header of the section file written by -split,
+// resolved against the directory of the file since the paths are recorded relative to it.
+// It reports false if the file is not generated by goconfig -split.
+func readSplitDest(fileName string) (string, bool, error) {
+	line, generated, err := readGeneratedArgs(fileName)
+	if err != nil || !generated {
+		return "", false, err
+	}
+	fs, _, gen, err := parseHeaderFlags(line)
+	if err != nil || !*gen.split {
+		// edited by hand or by another version, not ours to remove
+		return "", false, nil
+	}
+	dir := filepath.Dir(fileName)
+	resolve := func(p string) string {
+		if filepath.IsAbs(p) {
+			return p
+		}
+		return filepath.Join(dir, filepath.FromSlash(p))
+	}
+	if *gen.output != "" {
+		return resolve(*gen.output), true, nil
+	}
+	args := make([]string, fs.NArg())
+	for i, x := range fs.Args() {
+		args[i] = resolve(x)
+	}
+	if len(args) == 0 {
+		args = []string{dir}
+	}
+	return destFilename("", args), true, nil
+}
+
+// parseBuildTags returns the normalized build constraint expression of -tags.
+func parseBuildTags(v string) (string, error) {
+	if v == "" {
+		return "", nil
+	}
+	x, err := constraint.Parse("//go:build " + v)
+	if err != nil {
+		return "", fmt.Errorf("invalid tags %q: %w", v, err)
+	}
+	return x.String(), nil
+}
+
+// readLicense reads the license header from fileName.
+// The lines are commented out by "// " unless the file is already a comment.
+func readLicense(fileName string) (string, error) {
+	if fileName == "" {
+		return "", nil
+	}
+	b, err := os.ReadFile(fileName)
+	if err != nil {
+		return "", fmt.Errorf("failed to read header: %w", err)
+	}
+	v := strings.TrimRight(strings.ReplaceAll(string(b), "\r\n", "\n"), "\n\t ")
+	if v == "" || strings.HasPrefix(v, "//") || strings.HasPrefix(v, "/*") {
+		return v, nil
+	}
+	lines := strings.Split(v, "\n")
+	for i, x := range lines {
+		lines[i] = strings.TrimRight("// "+x, " ")
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// pathFlags are the flags of paths, recorded relative to the directory of the output.
+var pathFlags = []string{
+	"output",
+	"templates",
+	"header",
+}
+
+// recordArgs returns the arguments of the command recorded in the header, reproducible on any machine:
+// the flags set are sorted by name, the paths are relative to dir, the directory of the output,
+// and the values are quoted like shell words.
+func recordArgs(fs *flag.FlagSet, dir string) []string {
+	var args []string
+	fs.Visit(func(f *flag.Flag) {
+		if isBoolFlag(f) {
+			if f.Value.String() == "true" {
+				args = append(args, "-"+f.Name)
+			} else {
+				args = append(args, "-"+f.Name+"=false")
+			}
+			return
+		}
+		v := f.Value.String()
+		if v != "" && slices.Contains(pathFlags, f.Name) {
+			v = relativePath(dir, v)
+		}
+		args = append(args, "-"+f.Name, shellQuote(v))
+	})
+	for _, x := range fs.Args() {
+		if _, err := os.Stat(x); err == nil {
+			x = relativePath(dir, x)
+		}
+		args = append(args, shellQuote(x))
+	}
+	return args
+}
+
+func isBoolFlag(f *flag.Flag) bool {
+	x, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && x.IsBoolFlag()
+}
+
+// relativePath returns p relative to dir with slashes, or p as it is if impossible.
+func relativePath(dir, p string) string {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return p
+	}
+	absPath, err := filepath.Abs(p)
+	if err != nil {
+		return p
+	}
+	r, err := filepath.Rel(absDir, absPath)
+	if err != nil {
+		return p
+	}
+	return filepath.ToSlash(r)
+}
+
+// shellQuote quotes v by single quotes if it is not a plain word.
+func shellQuote(v string) string {
+	if v != "" && strings.IndexFunc(v, func(r rune) bool {
+		return !isPlainWordRune(r)
+	}) < 0 {
+		return v
+	}
+	return "'" + strings.ReplaceAll(v, "'", `'\''`) + "'"
+}
+
+func isPlainWordRune(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_=@%+,.:/", r)
+}
+
+var errUnterminatedQuote = errors.New("unterminated quote")
+
+// shellSplit splits v into the words quoted by shellQuote.
+func shellSplit(v string) ([]string, error) {
+	var (
+		words  []string
+		word   strings.Builder
+		inWord bool
+		quoted bool
+		escape bool
+	)
+	for _, r := range v {
+		switch {
+		case quoted:
+			if r == '\'' {
+				quoted = false
+			} else {
+				word.WriteRune(r)
+			}
+		case escape:
+			word.WriteRune(r)
+			escape = false
+		case r == '\\':
+			escape = true
+			inWord = true
+		case r == '\'':
+			quoted = true
+			inWord = true
+		case r == ' ' || r == '\t':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quoted || escape {
+		return nil, errUnterminatedQuote
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShellQuote(t *testing.T) {
+	for _, tc := range []struct {
+		v    string
+		want string
+	}{
+		{v: "-option", want: "-option"},
+		{v: "config.go", want: "config.go"},
+		{v: "", want: "''"},
+		{v: "Size int", want: "'Size int'"},
+		{v: `Host string @default="localhost"`, want: `'Host string @default="localhost"'`},
+		{v: "it's", want: `'it'\''s'`},
+	} {
+		t.Run(tc.v, func(t *testing.T) {
+			got := shellQuote(tc.v)
+			assert.Equal(t, tc.want, got)
+			words, err := shellSplit(got)
+			assert.Nil(t, err)
+			assert.Equal(t, []string{tc.v}, words)
+		})
+	}
+
+	_, err := shellSplit("-field 'Size int")
+	assert.ErrorIs(t, err, errUnterminatedQuote)
+}
+
+func TestParseBuildTags(t *testing.T) {
+	got, err := parseBuildTags("linux&&!nogoconfig")
+	assert.Nil(t, err)
+	assert.Equal(t, "linux && !nogoconfig", got)
+
+	got, err = parseBuildTags("")
+	assert.Nil(t, err)
+	assert.Equal(t, "", got)
+
+	_, err = parseBuildTags("linux &&")
+	assert.NotNil(t, err)
+}
+
+func TestReadLicense(t *testing.T) {
+	dir := t.TempDir()
+	for _, tc := range []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{name: "text", content: "Copyright 2026 Example\n\nLicensed under MIT.\n", want: "// Copyright 2026 Example\n//\n// Licensed under MIT."},
+		{name: "line comment", content: "// Copyright 2026 Example\n", want: "// Copyright 2026 Example"},
+		{name: "block comment", content: "/*\nCopyright 2026 Example\n*/\n", want: "/*\nCopyright 2026 Example\n*/"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			p := filepath.Join(dir, tc.name)
+			assert.Nil(t, os.WriteFile(p, []byte(tc.content), 0600))
+			got, err := readLicense(p)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestRecordArgs(t *testing.T) {
+	dir := t.TempDir()
+	pkg := filepath.Join(dir, "pkg")
+	assert.Nil(t, os.Mkdir(pkg, 0755))
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	_ = newSpecFlags(fs)
+	_ = newGenFlags(fs)
+	assert.Nil(t, fs.Parse([]string{
+		"-option",
+		"-output", filepath.Join(pkg, "config.go"),
+		"-header", filepath.Join(dir, "LICENSE"),
+		"-field", "Size int",
+		"-env=false",
+		pkg,
+	}))
+	assert.Equal(t, []string{
+		"-env=false",
+		"-field", "'Size int'",
+		"-header", "../LICENSE",
+		"-option",
+		"-output", "config.go",
+		".",
+	}, recordArgs(fs, pkg))
+}
+
+func TestFileHeader(t *testing.T) {
+	got := fileHeader{
+		buildTags: "linux",
+		license:   "/* License */",
+		args:      []string{"-field", "'Size int'"},
+		pkgName:   "example",
+	}.String()
+	assert.Equal(t, `//go:build linux
+
+/* License */
+
+// Code generated by "goconfig -field 'Size int'"; DO NOT EDIT.
+
+package example
+
+`, got)
+}
@@ -2,140 +2,514 @@ package main
 
 import (
 	"bytes"
+	"errors"
 	"flag"
 	"fmt"
+	"go/ast"
 	"go/parser"
-	"io"
+	"go/token"
+	"go/types"
 	"log"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"text/template"
+	"unicode"
 
 	"golang.org/x/tools/go/packages"
 )
 
-const usage = `Usage of goconfig:
-  goconfig [flags] -field F [directory]
+const specUsage = `F is list of "fieldName typeName [@tag...]" separated by "|".
 
-F is list of "fieldName typeName" separated by "|".
+Tags:
+  @doc=text
+    Document the field. text can be a Go string literal.
 
-Environment variables:
+  @default=expr
+    Go expression of the default value set by the builder constructor,
+    e.g. @default=10, @default="localhost".
+
+  @min=number, @max=number
+    Minimum and maximum of the value, or of the length for strings and slices.
+    Exported to the documentation and the JSON Schema.
+
+  @enum=a,b,c
+    Allowed values. Exported to the documentation and the JSON Schema.
+
+  @deprecated=text
+    Mark the field deprecated with the "Deprecated:" comment.
+    LoadEnv and LoadDir warn when the field is set.
+
+  @alias=OldName,...
+    Old names of the field. Generate deprecated builder methods, WithOldName
+    options and accessors forwarding to the field.
+    LoadEnv and LoadDir also read the old names with warnings;
+    the new names win if both are given.
+    The warnings are logged once by ConfigDeprecationLogger.
+
+  @secret
+    Show [REDACTED] instead of the value in String, GoString, LogValue,
+    MarshalJSON and Diff.
+    LoadEnv also reads the value from the file given by NAME_FILE.`
+
+const envUsage = `Environment variables:
   GOCONFIG_DEBUG
     If set, enable debug logs.
 
   GOCONFIG_STDOUT
-    If set, write result to stdout.
+    If set, write result to stdout instead of the files,
+    each after the line "==> NAME <==" if there are multiple files.`
 
-Flags:`
+var debugf = func(format string, v ...any) {}
 
-func Usage() {
-	fmt.Fprintln(os.Stderr, usage)
-	flag.PrintDefaults()
+// specFlags are the flags to specify the config, shared by the modes.
+type specFlags struct {
+	fields            *string
+	configType        *string
+	configItemType    *string
+	configBuilderType *string
+	configOptionType  *string
+	needOption        *bool
+	needFreeze        *bool
+	needTx            *bool
+	needClone         *bool
+	needReset         *bool
+	needValues        *bool
+	needEnv           *bool
+	envPrefix         *string
+	needDir           *bool
+	needAccessor      *bool
+	needReader        *bool
+	needOverride      *bool
+	needContext       *bool
+	schemaVersion     *int
+	typePrefix        *string
 }
 
-var debugf = func(format string, v ...any) {}
+func newSpecFlags(fs *flag.FlagSet) *specFlags {
+	return &specFlags{
+		fields:            fs.String("field", "", "list of fields by '|'; must be set"),
+		configType:        fs.String("config", "Config", "type name of config"),
+		configItemType:    fs.String("configItem", "ConfigItem", "type name of config item"),
+		configBuilderType: fs.String("configBuilder", "ConfigBuilder", "type name of config builder"),
+		configOptionType:  fs.String("configOption", "ConfigOption", "type name of config option"),
+		needOption:        fs.Bool("option", false, "generate option functions as WithXXX style"),
+		needFreeze:        fs.Bool("freeze", false, "generate Freeze method to make config read-only"),
+		needTx:            fs.Bool("tx", false, "generate ApplyTx method to apply options all-or-nothing; requires -option"),
+		needClone:         fs.Bool("clone", false, "generate Clone, Equal and Diff methods"),
+		needReset:         fs.Bool("reset", false, "generate Reset methods to discard modifications"),
+		needValues:        fs.Bool("values", false, "generate ToBuilder and Values methods and values type"),
+		needEnv:           fs.Bool("env", false, "generate LoadEnv method to load values from environment variables"),
+		envPrefix:         fs.String("envPrefix", "", "prefix of environment variable names"),
+		needDir:           fs.Bool("dir", false, "generate LoadDir method to load values from files in a directory"),
+		needAccessor:      fs.Bool("accessor", false, "generate accessor methods as the public API and make items unexported"),
+		needReader:        fs.Bool("reader", false, "generate read-only interface and fake implementation; requires -accessor"),
+		needOverride:      fs.Bool("override", false, "generate Override method to apply options until the test finishes; requires -option"),
+		needContext:       fs.Bool("context", false, "generate functions to carry config in context.Context; requires -option"),
+		schemaVersion:     fs.Int("schemaVersion", 0, "version of config documents; if positive, generate LoadJSON method with migrations"),
+		typePrefix:        fs.String("prefix", "", "prefix for generated types"),
+	}
+}
 
-func main() {
-	var (
-		fields            = flag.String("field", "", "list of fields by '|'; must be set")
-		configType        = flag.String("config", "Config", "type name of config")
-		configItemType    = flag.String("configItem", "ConfigItem", "type name of config item")
-		configBuilderType = flag.String("configBuilder", "ConfigBuilder", "type name of config builder")
-		configOptionType  = flag.String("configOption", "ConfigOption", "type name of config option")
-		needOption        = flag.Bool("option", false, "generate option functions as WithXXX style")
-		output            = flag.String("output", "", "output file name; default srcdir/config.go")
-		typePrefix        = flag.String("prefix", "", "prefix for generated types")
+// typeNames returns the type names of the config, the item, the builder and the option with the prefix.
+func (s *specFlags) typeNames() (configType, configItemType, configBuilderType, configOptionType string) {
+	prefix := capitalize(*s.typePrefix)
+	return prefix + *s.configType, prefix + *s.configItemType, prefix + *s.configBuilderType, prefix + *s.configOptionType
+}
 
-		redirectToStdout = os.Getenv("GOCONFIG_STDOUT") != ""
-		debug            = os.Getenv("GOCONFIG_DEBUG") != ""
-	)
+func (s *specFlags) options() generatorOptions {
+	return generatorOptions{
+		option:        *s.needOption,
+		freeze:        *s.needFreeze,
+		tx:            *s.needTx,
+		clone:         *s.needClone,
+		reset:         *s.needReset,
+		values:        *s.needValues,
+		env:           *s.needEnv,
+		envPrefix:     *s.envPrefix,
+		dir:           *s.needDir,
+		accessor:      *s.needAccessor,
+		reader:        *s.needReader,
+		override:      *s.needOverride,
+		context:       *s.needContext,
+		schemaVersion: *s.schemaVersion,
+	}
+}
 
-	if debug {
-		debugf = log.Printf
+// validate returns an error if the flags are invalid.
+func (s *specFlags) validate() error {
+	if len(*s.fields) == 0 {
+		return fmt.Errorf("field option must be set")
 	}
+	return validateSpec(*s.fields, s.options())
+}
 
-	log.SetFlags(0)
-	log.SetPrefix("goconfig: ")
-	flag.Usage = Usage
-	flag.Parse()
+// validateSpec returns an error if the fields or the options are invalid, shared by the flags and the model.
+func validateSpec(fields string, opts generatorOptions) error {
+	if opts.tx && !opts.option {
+		return fmt.Errorf("tx option requires option option")
+	}
+	if opts.reader && !opts.accessor {
+		return fmt.Errorf("reader option requires accessor option")
+	}
+	if opts.override && !opts.option {
+		return fmt.Errorf("override option requires option option")
+	}
+	if opts.context && !opts.option {
+		return fmt.Errorf("context option requires option option")
+	}
+	if opts.schemaVersion < 0 {
+		return fmt.Errorf("schemaVersion option must not be negative")
+	}
+	fs, err := parseConfigFieldList(fields)
+	if err != nil {
+		return err
+	}
+	if opts.dir {
+		if err := checkFileKeys(fs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-	prefix := capitalize(*typePrefix)
-	for _, p := range []*string{
+// newGenerator returns the generator of the flags, or usageError if the flags are invalid.
+func (s *specFlags) newGenerator() (*generator, error) {
+	if err := s.validate(); err != nil {
+		return nil, &usageError{err: err}
+	}
+	configType, configItemType, configBuilderType, configOptionType := s.typeNames()
+	return newGenerator(
+		*s.fields,
 		configType,
 		configItemType,
 		configBuilderType,
 		configOptionType,
-	} {
-		*p = fmt.Sprintf("%s%s", prefix, *p)
+		s.options(),
+	), nil
+}
+
+// generate returns the generator that has generated the code of the packages of fs.Args().
+// The flags of fs are recorded in the header.
+func (s *genFlags) generate(spec *specFlags, fs *flag.FlagSet) (*generator, error) {
+	g, err := spec.newGenerator()
+	if err != nil {
+		return nil, err
+	}
+	buildTags, err := parseBuildTags(*s.tags)
+	if err != nil {
+		return nil, &usageError{err: err}
+	}
+	license, err := readLicense(*s.header)
+	if err != nil {
+		return nil, err
+	}
+	t, err := loadTemplates(*s.templates)
+	if err != nil {
+		return nil, err
+	}
+	g.templates = t
+	g.plugins = splitPlugins(*s.plugins)
+	g.parsePackage(fs.Args(), (*s.needSchema || *s.needExample) && g.conf.hasOpaqueType())
+
+	g.header = fileHeader{
+		buildTags: buildTags,
+		license:   license,
+		args:      recordArgs(fs, filepath.Dir(destFilename(*s.output, fs.Args()))),
+		pkgName:   g.pkgName,
+	}.String()
+	g.Print(g.header)
+
+	if err := g.generate(); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// genFlags are the flags of gen, also accepted by check.
+type genFlags struct {
+	output      *string
+	templates   *string
+	plugins     *string
+	needSchema  *bool
+	needExample *bool
+	emitModel   *bool
+	split       *bool
+	tags        *string
+	header      *string
+}
+
+func newGenFlags(fs *flag.FlagSet) *genFlags {
+	return &genFlags{
+		output:      fs.String("output", "", "output file name; default srcdir/config.go"),
+		templates:   fs.String("templates", "", "directory of the templates item.tmpl, config.tmpl, builder.tmpl and option.tmpl overriding the default ones"),
+		plugins:     fs.String("plugin", "", "comma-separated names of the plugins to append their code; NAME runs goconfig-gen-NAME in PATH"),
+		needSchema:  fs.Bool("schema", false, "write JSON Schema of the config to config.schema.json next to the output"),
+		needExample: fs.Bool("emit-example", false, "write config.example.json and .env.example next to the output"),
+		emitModel:   fs.Bool("emit-model", false, "print the model of the config as JSON instead of generating"),
+		tags:        fs.String("tags", "", "build constraint of the generated files written as the //go:build line, e.g. 'linux && !nogoconfig'"),
+		header:      fs.String("header", "", "file of the license header of the generated files; commented out by // unless it is a comment"),
+		split:       fs.Bool("split", false, "write the sections to their own files, e.g. config_item.go and config_builder.go next to the output"),
 	}
+}
 
-	if len(*fields) == 0 {
-		log.Fatal("field option must be set")
+func main() {
+	if os.Getenv("GOCONFIG_DEBUG") != "" {
+		debugf = log.Printf
 	}
 
-	g := newGenerator(
-		*fields,
-		*configType,
-		*configItemType,
-		*configBuilderType,
-		*configOptionType,
-		*needOption,
+	log.SetFlags(0)
+	log.SetPrefix("goconfig: ")
+
+	os.Exit(runMain(os.Args[1:]))
+}
+
+func runGen(fs *flag.FlagSet, args []string) error {
+	var (
+		spec = newSpecFlags(fs)
+		gen  = newGenFlags(fs)
+
+		redirectToStdout = os.Getenv("GOCONFIG_STDOUT") != ""
 	)
-	g.parsePackage(flag.Args())
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if err := checkPackageArgs(fs.Args()); err != nil {
+		return err
+	}
 
-	g.Printf("// Code generated by \"goconfig %s\"; DO NOT EDIT.\n", strings.Join(os.Args[1:], " "))
-	g.Println()
-	g.Printf("package %s\n", g.pkgName)
-	g.Println()
+	if *gen.emitModel {
+		return printModel(spec, *gen.output, fs.Args())
+	}
 
-	g.generate()
+	g, err := gen.generate(spec, fs)
+	if err != nil {
+		return err
+	}
 
-	writeResult := func(src []byte, args []string) error {
-		if redirectToStdout {
-			return writeResultToStdout(src)
+	files, err := gen.outputs(g, fs.Args())
+	if err != nil {
+		return err
+	}
+	if redirectToStdout {
+		return writeFiles(os.Stdout, files)
+	}
+	stale, err := staleSectionFiles(destFilename(*gen.output, fs.Args()), files)
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		if written, err := writeFileIfChanged(f.name, f.content); err != nil {
+			return err
+		} else if !written {
+			debugf("%s is unchanged", f.name)
+		}
+	}
+	for _, x := range stale {
+		if err := os.Remove(x); err != nil {
+			return err
 		}
-		return writeResultToDestfile(src, *output, args)
+		debugf("%s is removed", x)
 	}
+	return nil
+}
 
-	if err := writeResult(g.bytes(), flag.Args()); err != nil {
-		log.Panic(err)
+// outputs returns the files generated by g: the code, split by the sections if -split, the schema and the examples.
+func (s *genFlags) outputs(g *generator, patterns []string) ([]outputFile, error) {
+	dest := destFilename(*s.output, patterns)
+	var files []outputFile
+	if *s.split {
+		names, sources := g.splitSources()
+		for _, name := range names {
+			fileName := sectionFilename(dest, name)
+			src, err := formatSource(sources[name], fileName)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, outputFile{name: fileName, content: src})
+		}
+	} else {
+		src, err := formatSource(g.bytes(), dest)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, outputFile{name: dest, content: src})
+	}
+	if *s.needSchema {
+		b, err := g.schema()
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, outputFile{name: schemaFilename(dest), content: b})
 	}
+	if *s.needExample {
+		e := g.example()
+		b, err := e.json()
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate example: %w", err)
+		}
+		jsonFilename, envFilename := exampleFilenames(dest)
+		files = append(files,
+			outputFile{name: jsonFilename, content: b},
+			outputFile{name: envFilename, content: []byte(e.dotenv())},
+		)
+	}
+	return files, nil
 }
 
-func writeResultToStdout(src []byte) error {
-	f, err := os.CreateTemp("", "goconfig")
-	if err != nil {
-		return fmt.Errorf("failed to create temp file: %w", err)
+func runCheck(fs *flag.FlagSet, args []string) error {
+	var (
+		spec = newSpecFlags(fs)
+		gen  = newGenFlags(fs)
+	)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if err := checkPackageArgs(fs.Args()); err != nil {
+		return err
 	}
-	defer os.Remove(f.Name())
 
-	if err := writeResultAndFormat(src, f.Name()); err != nil {
+	g, err := gen.generate(spec, fs)
+	if err != nil {
 		return err
 	}
-	if _, err := f.Seek(0, os.SEEK_SET); err != nil {
+	files, err := gen.outputs(g, fs.Args())
+	if err != nil {
 		return err
 	}
-	if _, err := io.Copy(os.Stdout, f); err != nil {
+	var outdated []string
+	for _, f := range files {
+		current, err := os.ReadFile(f.name)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		if !bytes.Equal(current, f.content) {
+			outdated = append(outdated, f.name)
+		}
+	}
+	stale, err := staleSectionFiles(destFilename(*gen.output, fs.Args()), files)
+	if err != nil {
 		return err
 	}
+	for _, x := range stale {
+		outdated = append(outdated, x+" (no longer generated)")
+	}
+	if len(outdated) > 0 {
+		return fmt.Errorf("%w: %s", errOutdated, strings.Join(outdated, ", "))
+	}
 	return nil
 }
 
-func writeResultToDestfile(src []byte, output string, args []string) error {
-	return writeResultAndFormat(src, destFilename(output, args))
+func runModel(fs *flag.FlagSet, args []string) error {
+	var (
+		spec   = newSpecFlags(fs)
+		output = fs.String("output", "", "output file name of the generated code; default srcdir/config.go")
+	)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if err := checkPackageArgs(fs.Args()); err != nil {
+		return err
+	}
+	return printModel(spec, *output, fs.Args())
+}
+
+// printModel prints the model of the package of args as JSON.
+func printModel(spec *specFlags, output string, args []string) error {
+	g, err := spec.newGenerator()
+	if err != nil {
+		return err
+	}
+	g.parsePackage(args, false)
+	m := g.model()
+	m.Output = destFilename(output, args)
+	b, err := marshalIndent(m)
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(b)
+	return err
+}
+
+// example returns the examples of the config.
+func (s *generator) example() *configExample {
+	return &configExample{
+		config:        s.conf,
+		schemaVersion: s.opts.schemaVersion,
+		env:           &configEnv{config: s.conf, prefix: s.opts.envPrefix},
+		loadEnv:       s.env != nil,
+	}
 }
 
-func writeResultAndFormat(src []byte, fileName string) error {
-	if err := os.WriteFile(fileName, src, 0600); err != nil {
-		return fmt.Errorf("failed to write to %s: %w", fileName, err)
+func runSchema(fs *flag.FlagSet, args []string) error {
+	var (
+		spec   = newSpecFlags(fs)
+		output = fs.String("output", "", "output file name; default stdout")
+	)
+	if err := parseFlags(fs, args); err != nil {
+		return err
 	}
-	cmd := exec.Command("go", "run", "golang.org/x/tools/cmd/goimports@v0.7.0", "-w", fileName)
-	cmd.Stderr = os.Stderr
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to goimport: %w", err)
+	if err := checkPackageArgs(fs.Args()); err != nil {
+		return err
 	}
-	return nil
+	g, err := spec.newGenerator()
+	if err != nil {
+		return err
+	}
+	if g.conf.hasOpaqueType() {
+		g.parsePackage(fs.Args(), true)
+	}
+	b, err := g.schema()
+	if err != nil {
+		return err
+	}
+	return writeOutput(*output, b)
+}
+
+func runExample(fs *flag.FlagSet, args []string) error {
+	var (
+		spec   = newSpecFlags(fs)
+		format = fs.String("format", "json", "output format; json or env")
+		output = fs.String("output", "", "output file name; default stdout")
+	)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if err := checkPackageArgs(fs.Args()); err != nil {
+		return err
+	}
+	g, err := spec.newGenerator()
+	if err != nil {
+		return err
+	}
+	if *format == "json" && g.conf.hasOpaqueType() {
+		g.parsePackage(fs.Args(), true)
+	}
+	var b []byte
+	switch *format {
+	case "json":
+		b, err = g.example().json()
+		if err != nil {
+			return fmt.Errorf("failed to generate example: %w", err)
+		}
+	case "env":
+		b = []byte(g.example().dotenv())
+	default:
+		return newUsageError("unknown format: %s", *format)
+	}
+	return writeOutput(*output, b)
+}
+
+// schema returns the JSON Schema of the config.
+func (s *generator) schema() ([]byte, error) {
+	b, err := (&configSchema{config: s.conf, schemaVersion: s.opts.schemaVersion}).generate()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate schema: %w", err)
+	}
+	return b, nil
 }
 
 func destFilename(output string, args []string) string {
@@ -145,6 +519,83 @@ func destFilename(output string, args []string) string {
 	return filepath.Join(destDir(args), "config.go")
 }
 
+// sectionFilename returns the file of the section next to dest,
+// e.g. config_item.go for the section item of config.go, and dest itself for the main section.
+func sectionFilename(dest, section string) string {
+	if section == "" {
+		return dest
+	}
+	return strings.TrimSuffix(dest, filepath.Ext(dest)) + "_" + section + filepath.Ext(dest)
+}
+
+// sectionNames are the names of the sections written to their own files by -split, except the main and the plugins.
+var sectionNames = []string{
+	"item",
+	"builder",
+	"option",
+	"freeze",
+	"clone",
+	"tx",
+	"reset",
+	"values",
+	"env",
+	"dir",
+	"json",
+	"deprecation",
+	"reader",
+	"override",
+	"context",
+}
+
+// staleSectionFiles returns the files of the sections next to dest generated by goconfig -split for dest but not in files,
+// e.g. config_env.go after -env or -split is dropped.
+// The files of the unknown sections, e.g. config_server.go, and the files of another config,
+// e.g. config_env.go generated by -output config_env.go, are not returned.
+func staleSectionFiles(dest string, files []outputFile) ([]string, error) {
+	var (
+		ext    = filepath.Ext(dest)
+		prefix = strings.TrimSuffix(filepath.Base(dest), ext) + "_"
+	)
+	entries, err := os.ReadDir(filepath.Dir(dest))
+	if err != nil {
+		return nil, err
+	}
+	var stale []string
+	for _, e := range entries {
+		name, ok := strings.CutPrefix(e.Name(), prefix)
+		if !ok || e.IsDir() {
+			continue
+		}
+		section, ok := strings.CutSuffix(name, ext)
+		if !ok || !(slices.Contains(sectionNames, section) || strings.HasSuffix(section, "_plugin")) {
+			continue
+		}
+		fileName := sectionFilename(dest, section)
+		if slices.ContainsFunc(files, func(f outputFile) bool { return f.name == fileName }) {
+			continue
+		}
+		if x, ok, err := readSplitDest(fileName); err != nil {
+			return nil, err
+		} else if ok && samePath(x, dest) {
+			stale = append(stale, fileName)
+		}
+	}
+	return stale, nil
+}
+
+// samePath reports whether a and b are the same path.
+func samePath(a, b string) bool {
+	x, err := filepath.Abs(a)
+	if err != nil {
+		return false
+	}
+	y, err := filepath.Abs(b)
+	if err != nil {
+		return false
+	}
+	return x == y
+}
+
 func destDir(args []string) string {
 	if len(args) == 0 {
 		args = []string{"."}
@@ -157,10 +608,41 @@ func destDir(args []string) string {
 
 func isDirectory(p string) bool {
 	x, err := os.Stat(p)
-	if err != nil {
-		log.Fatalf("directory: %v", err)
+	return err == nil && x.IsDir()
+}
+
+// checkPackageArgs returns a usage error if any of args, the directory or the files of the package, does not exist.
+func checkPackageArgs(args []string) error {
+	for _, x := range args {
+		if _, err := os.Stat(x); err != nil {
+			return newUsageError("directory: %v", err)
+		}
 	}
-	return x.IsDir()
+	return nil
+}
+
+// generatorOptions switches optional sections of the generated code.
+type generatorOptions struct {
+	option bool
+	freeze bool
+	tx     bool
+	clone  bool
+	reset  bool
+	values bool
+	env    bool
+	// envPrefix is the prefix of the environment variable names.
+	envPrefix string
+	dir       bool
+	// accessor makes the items unexported and generates the accessor methods.
+	accessor bool
+	// reader generates the interface of the accessors and its fake; requires accessor.
+	reader bool
+	// override generates Override for tests; requires option.
+	override bool
+	// context generates the functions to carry the config in context.Context; requires option.
+	context bool
+	// schemaVersion is the version of the config documents, LoadJSON is generated if positive.
+	schemaVersion int
 }
 
 func newGenerator(
@@ -169,7 +651,7 @@ func newGenerator(
 	configItemType,
 	configBuilderType,
 	configOptionType string,
-	needOption bool,
+	opts generatorOptions,
 ) *generator {
 	item := &configItem{
 		typeName:    configItemType,
@@ -179,7 +661,9 @@ func newGenerator(
 		typeName:   configType,
 		configItem: item,
 		fields:     parseConfigFields(fields),
+		accessor:   opts.accessor,
 	}
+	item.needSecret = conf.hasSecret()
 	builder := &configBuilder{
 		typeName:    configBuilderType,
 		config:      conf,
@@ -189,34 +673,186 @@ func newGenerator(
 		typeName: configOptionType,
 		config:   conf,
 	}
+	var freeze *configFreeze
+	if opts.freeze {
+		freeze = &configFreeze{
+			tokenType: fmt.Sprintf("%sUnfreezeToken", configType),
+			errName:   fmt.Sprintf("Err%sFrozen", configType),
+			config:    conf,
+		}
+		if opts.option {
+			freeze.option = option
+		}
+		item.freeze = freeze
+		conf.freeze = freeze
+	}
+	var clone *configClone
+	if opts.tx || opts.clone || opts.context || opts.schemaVersion > 0 {
+		clone = &configClone{
+			config:   conf,
+			diffType: fmt.Sprintf("%sFieldDiff", configType),
+			public:   opts.clone,
+		}
+		item.needClone = true
+		item.needEqual = opts.tx || opts.clone || opts.context
+	}
+	var tx *configTx
+	if opts.tx {
+		tx = &configTx{
+			config: conf,
+			option: option,
+			freeze: freeze,
+		}
+	}
+	var reset *configReset
+	if opts.reset {
+		reset = &configReset{
+			config: conf,
+			freeze: freeze,
+		}
+		item.needReset = true
+	}
+	var values *configValues
+	if opts.values {
+		values = &configValues{
+			typeName: fmt.Sprintf("%sValues", configType),
+			config:   conf,
+			builder:  builder,
+		}
+		if opts.option {
+			values.option = option
+		}
+	}
+	item.needDeepCopy = (clone != nil || values != nil) && conf.hasOpaqueType()
+	var deprecation *configDeprecation
+	if (opts.env || opts.dir || opts.schemaVersion > 0) && conf.hasDeprecation() {
+		deprecation = &configDeprecation{
+			loggerName: fmt.Sprintf("%sDeprecationLogger", configType),
+			warnFunc:   fmt.Sprintf("warn%sDeprecation", configType),
+			warnedName: fmt.Sprintf("%sDeprecationWarned", decapitalize(configType)),
+		}
+	}
+	var env *configEnv
+	if opts.env {
+		env = &configEnv{
+			config:      conf,
+			prefix:      opts.envPrefix,
+			deprecation: deprecation,
+		}
+		item.needLoader = true
+	}
+	var dir *configDir
+	if opts.dir {
+		dir = &configDir{
+			config:      conf,
+			deprecation: deprecation,
+		}
+		item.needLoader = true
+	}
+	var jsonLoader *configJSON
+	if opts.schemaVersion > 0 {
+		jsonLoader = &configJSON{
+			config:         conf,
+			schemaVersion:  opts.schemaVersion,
+			versionName:    fmt.Sprintf("%sSchemaVersion", configType),
+			migrationsName: fmt.Sprintf("%sMigrations", decapitalize(configType)),
+			registerName:   fmt.Sprintf("Register%sMigration", configType),
+			deprecation:    deprecation,
+			freeze:         freeze,
+		}
+		item.needLoader = true
+		item.needJSONLoader = true
+	}
+	var reader *configReader
+	if opts.reader {
+		reader = &configReader{
+			typeName: fmt.Sprintf("%sReader", configType),
+			fakeName: fmt.Sprintf("Fake%s", configType),
+			config:   conf,
+		}
+	}
+	var override *configOverride
+	if opts.override {
+		override = &configOverride{
+			config: conf,
+			option: option,
+		}
+	}
+	var ctx *configContext
+	if opts.context {
+		ctx = &configContext{
+			keyType: fmt.Sprintf("%sContextKey", decapitalize(configType)),
+			config:  conf,
+			option:  option,
+		}
+	}
 	var b bytes.Buffer
 	return &generator{
-		buf:        b,
-		item:       item,
-		conf:       conf,
-		builder:    builder,
-		option:     option,
-		needOption: needOption,
+		buf:         b,
+		item:        item,
+		conf:        conf,
+		builder:     builder,
+		option:      option,
+		freeze:      freeze,
+		clone:       clone,
+		tx:          tx,
+		reset:       reset,
+		values:      values,
+		env:         env,
+		dir:         dir,
+		reader:      reader,
+		override:    override,
+		context:     ctx,
+		jsonLoader:  jsonLoader,
+		deprecation: deprecation,
+		opts:        opts,
+		templates:   defaultTemplates(),
 	}
 }
 
 type generator struct {
-	buf        bytes.Buffer
-	pkgName    string
-	item       *configItem
-	conf       *config
-	builder    *configBuilder
-	option     *configOption
-	needOption bool
+	buf         bytes.Buffer
+	pkgName     string
+	item        *configItem
+	conf        *config
+	builder     *configBuilder
+	option      *configOption
+	freeze      *configFreeze
+	clone       *configClone
+	tx          *configTx
+	reset       *configReset
+	values      *configValues
+	env         *configEnv
+	dir         *configDir
+	reader      *configReader
+	override    *configOverride
+	context     *configContext
+	jsonLoader  *configJSON
+	deprecation *configDeprecation
+	opts        generatorOptions
+	// templates generate the item, the config, the builder and the option.
+	templates *template.Template
+	// plugins are the names of the plugins whose code is appended.
+	plugins []string
+	// header is the comment and the package clause of the generated files.
+	header   string
+	sections []section
 }
 
 func (s *generator) Printf(format string, v ...any) { fmt.Fprintf(&s.buf, format, v...) }
 func (s *generator) Print(v string)                 { fmt.Fprint(&s.buf, v) }
 func (s *generator) Println(v ...any)               { fmt.Fprintln(&s.buf, v...) }
 
-func (s *generator) parsePackage(patterns []string) {
+// parsePackage loads the package of patterns.
+// If needTypes, the types of the package are also loaded from the source, even if ill-typed,
+// to resolve the named types in the schema.
+func (s *generator) parsePackage(patterns []string, needTypes bool) {
+	mode := packages.NeedName
+	if needTypes {
+		mode |= packages.NeedTypes | packages.NeedSyntax
+	}
 	pkgs, err := packages.Load(&packages.Config{
-		Mode: packages.NeedName,
+		Mode: mode,
 	}, patterns...)
 	if err != nil {
 		log.Fatalf("load: %v", err)
@@ -225,16 +861,112 @@ func (s *generator) parsePackage(patterns []string) {
 		log.Fatalf("%d packages found", len(pkgs))
 	}
 	s.pkgName = pkgs[0].Name
+	s.conf.types = pkgs[0].Types
 	debugf("Found package: %s", s.pkgName)
 }
 
-func (s *generator) generate() {
-	s.Print(s.item.generate())
-	s.Print(s.conf.generate())
-	s.Print(s.builder.generate())
-	if s.needOption {
-		s.Print(s.option.generate())
+// generate generates the code of the sections.
+// It returns the errors of the templates and the plugins.
+func (s *generator) generate() error {
+	for _, x := range []struct {
+		section  string
+		template string
+	}{
+		{section: "item", template: "item.tmpl"},
+		{section: "", template: "config.tmpl"},
+		{section: "builder", template: "builder.tmpl"},
+		{section: "option", template: "option.tmpl"},
+	} {
+		if x.section == "option" && !s.opts.option {
+			continue
+		}
+		code, err := s.execute(x.template)
+		if err != nil {
+			return err
+		}
+		s.addSection(x.section, code)
+	}
+	if s.opts.freeze {
+		s.addSection("freeze", s.freeze.generate())
+	}
+	if s.clone != nil {
+		s.addSection("clone", s.clone.generate())
+	}
+	if s.opts.tx {
+		s.addSection("tx", s.tx.generate())
+	}
+	if s.opts.reset {
+		s.addSection("reset", s.reset.generate())
+	}
+	if s.opts.values {
+		s.addSection("values", s.values.generate())
+	}
+	if s.opts.env {
+		s.addSection("env", s.env.generate())
+	}
+	if s.opts.dir {
+		s.addSection("dir", s.dir.generate())
+	}
+	if s.jsonLoader != nil {
+		s.addSection("json", s.jsonLoader.generate())
+	}
+	if s.deprecation != nil {
+		s.addSection("deprecation", s.deprecation.generate())
+	}
+	if s.opts.reader {
+		s.addSection("reader", s.reader.generate())
+	}
+	if s.opts.override {
+		s.addSection("override", s.override.generate())
+	}
+	if s.opts.context {
+		s.addSection("context", s.context.generate())
+	}
+	for _, p := range s.plugins {
+		code, err := runPlugin(p, s.model())
+		if err != nil {
+			return err
+		}
+		// not config_NAME.go, which may be a build constraint like config_linux.go
+		s.addSection(p+"_plugin", code)
+	}
+	return nil
+}
+
+// section is the code of a section of the generator, written to its own file by -split.
+type section struct {
+	// name is the suffix of the file name, empty for the main file.
+	name string
+	code string
+}
+
+// addSection appends the code of the section, ignored if empty.
+func (s *generator) addSection(name, code string) {
+	if code == "" {
+		return
+	}
+	s.Print(code)
+	s.sections = append(s.sections, section{
+		name: name,
+		code: code,
+	})
+}
+
+// splitSources returns the code of each section with the header, the main file first.
+// The code of the sections of the same name are concatenated.
+func (s *generator) splitSources() (names []string, sources map[string][]byte) {
+	names = []string{""}
+	sources = map[string][]byte{}
+	for _, x := range s.sections {
+		if _, ok := sources[x.name]; !ok {
+			if x.name != "" {
+				names = append(names, x.name)
+			}
+			sources[x.name] = []byte(s.header)
+		}
+		sources[x.name] = append(sources[x.name], x.code...)
 	}
+	return
 }
 
 func (s *generator) bytes() []byte { return s.buf.Bytes() }
@@ -253,38 +985,20 @@ func (s *stringBuilder) write(v string) {
 type configItem struct {
 	typeName    string
 	constructor string
+	freeze      *configFreeze
+	needClone   bool
+	needEqual   bool
+	// needDeepCopy requires the copy function of the values of the opaque types, see isOpaqueType.
+	needDeepCopy bool
+	needReset    bool
+	needSecret   bool
+	needLoader   bool
+	// needJSONLoader requires needLoader.
+	needJSONLoader bool
 }
 
-func (s *configItem) generate() string {
-	recv := fmt.Sprintf("(s *%s[T])", s.typeName)
-	return fmt.Sprintf(`type %[1]s[T any] struct {
-  modified bool
-  value T
-  defaultValue T
-}
-func %[2]s Set(value T) {
-  s.modified = true
-  s.value = value
-}
-func %[2]s Get() T {
-  if s.modified {
-    return s.value
-  }
-  return s.defaultValue
-}
-func %[2]s Default() T {
-  return s.defaultValue
-}
-func %[2]s IsModified() bool {
-  return s.modified
-}
-func %[3]s[T any](defaultValue T) *%[1]s[T] {
-  return &%[1]s[T]{
-    defaultValue: defaultValue,
-  }
-}
-`, s.typeName, recv, s.constructor)
-}
+// redacted is shown instead of the values of the secret fields.
+const redacted = "[REDACTED]"
 
 func capitalize(v string) string {
 	if v == "" {
@@ -293,6 +1007,15 @@ func capitalize(v string) string {
 	return fmt.Sprintf("%s%s", strings.ToUpper(string(v[0])), v[1:])
 }
 
+// unexportedName returns the decapitalized name, with suffix if it is a keyword, e.g. typeItem for Type.
+func unexportedName(name, suffix string) string {
+	v := decapitalize(name)
+	if token.IsKeyword(v) {
+		return v + suffix
+	}
+	return v
+}
+
 func decapitalize(v string) string {
 	if v == "" {
 		return ""
@@ -308,53 +1031,356 @@ func parseConfigField(field string) (*configField, error) {
 
 	fieldName := xs[0]
 	typeName := xs[1]
+	var tags map[string]string
+	if i := strings.Index(typeName, "@"); i >= 0 {
+		t, err := parseFieldTags(typeName[i:])
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse tags of field %s: %w", field, err)
+		}
+		tags = t
+		typeName = strings.TrimSpace(typeName[:i])
+	}
 
 	// validate typename
-	if _, err := parser.ParseExpr(typeName); err != nil {
+	typeExpr, err := parser.ParseExpr(typeName)
+	if err != nil {
 		return nil, fmt.Errorf("failed to parse field %s: %w", field, err)
 	}
-
-	return &configField{
+	if v, ok := tags["default"]; ok {
+		if _, err := parser.ParseExpr(v); err != nil {
+			return nil, fmt.Errorf("failed to parse default of field %s: %w", field, err)
+		}
+	}
+	for _, k := range []string{"min", "max"} {
+		if v, ok := tags[k]; ok {
+			if _, err := strconv.ParseFloat(v, 64); err != nil {
+				return nil, fmt.Errorf("%s of field %s must be a number: %w", k, field, err)
+			}
+		}
+	}
+	if v, ok := tags["alias"]; ok {
+		for _, x := range strings.Split(v, ",") {
+			if x = strings.TrimSpace(x); !token.IsIdentifier(x) {
+				return nil, fmt.Errorf("alias of field %s must be identifiers: %q", field, x)
+			}
+		}
+	}
+
+	return &configField{
 		fieldName: capitalize(fieldName), // as public field
 		typeName:  typeName,
+		typeExpr:  typeExpr,
+		tags:      tags,
 	}, nil
 }
 
+type fieldTagKind int
+
+const (
+	fieldTagFlag fieldTagKind = iota // takes no value
+	fieldTagText                     // takes text, unquoted if it is a Go string literal
+	fieldTagExpr                     // takes Go expression
+)
+
+var knownFieldTags = map[string]fieldTagKind{
+	"secret":     fieldTagFlag,
+	"doc":        fieldTagText,
+	"default":    fieldTagExpr,
+	"min":        fieldTagText,
+	"max":        fieldTagText,
+	"enum":       fieldTagText,
+	"deprecated": fieldTagText,
+	"alias":      fieldTagText,
+}
+
+// parseFieldTags parses tags like `@name @name=value @name="quoted value"`.
+func parseFieldTags(v string) (map[string]string, error) {
+	tags := map[string]string{}
+	for {
+		v = strings.TrimLeft(v, " ")
+		if v == "" {
+			return tags, nil
+		}
+		if v[0] != '@' {
+			return nil, fmt.Errorf("tag must start with @: %s", v)
+		}
+		v = v[1:]
+		i := strings.IndexAny(v, " =")
+		if i < 0 {
+			i = len(v)
+		}
+		name := v[:i]
+		v = v[i:]
+		kind, ok := knownFieldTags[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown tag: %s", name)
+		}
+		if _, ok := tags[name]; ok {
+			return nil, fmt.Errorf("duplicated tag: %s", name)
+		}
+		if !strings.HasPrefix(v, "=") {
+			if kind != fieldTagFlag {
+				return nil, fmt.Errorf("tag %s requires value", name)
+			}
+			tags[name] = ""
+			continue
+		}
+		if kind == fieldTagFlag {
+			return nil, fmt.Errorf("tag %s does not take value", name)
+		}
+		value, rest, err := parseFieldTagValue(v[1:])
+		if err != nil {
+			return nil, fmt.Errorf("tag %s: %w", name, err)
+		}
+		if kind == fieldTagText && value != "" && (value[0] == '"' || value[0] == '`') {
+			if value, err = strconv.Unquote(value); err != nil {
+				return nil, fmt.Errorf("tag %s: %w", name, err)
+			}
+		}
+		tags[name] = value
+		v = rest
+	}
+}
+
+// parseFieldTagValue returns the first value of v and the rest.
+// The value lasts until the next tag, spaces and @ in quotes are part of the value.
+func parseFieldTagValue(v string) (string, string, error) {
+	var quote byte
+	for i := 0; i < len(v); i++ {
+		switch c := v[i]; {
+		case quote == '"' && c == '\\':
+			i++
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '`':
+			quote = c
+		case c == '@' && i > 0 && v[i-1] == ' ':
+			return strings.TrimSpace(v[:i]), v[i:], nil
+		}
+	}
+	if quote != 0 {
+		return "", "", fmt.Errorf("unterminated string: %s", v)
+	}
+	return strings.TrimSpace(v), "", nil
+}
+
+// splitConfigFields splits fields by "|" except in quotes and brackets.
+func splitConfigFields(fields string) []string {
+	var (
+		ss    []string
+		start int
+		quote byte
+		depth int
+	)
+	for i := 0; i < len(fields); i++ {
+		switch c := fields[i]; {
+		case quote == '"' && c == '\\':
+			i++
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '`':
+			quote = c
+		case c == '(' || c == '[' || c == '{':
+			depth++
+		case c == ')' || c == ']' || c == '}':
+			depth--
+		case c == '|' && depth == 0:
+			ss = append(ss, fields[start:i])
+			start = i + 1
+		}
+	}
+	return append(ss, fields[start:])
+}
+
 func parseConfigFields(fields string) []*configField {
-	ss := strings.Split(fields, "|")
+	fs, err := parseConfigFieldList(fields)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return fs
+}
+
+func parseConfigFieldList(fields string) ([]*configField, error) {
+	ss := splitConfigFields(fields)
 	fs := make([]*configField, len(ss))
 	for i, s := range ss {
 		debugf("Parse field[%d]: %s", i, s)
 		f, err := parseConfigField(s)
 		if err != nil {
-			log.Fatalf("Failed to parse field[%d]: %v", i, err)
+			return nil, fmt.Errorf("failed to parse field[%d]: %w", i, err)
 		}
 		debugf("Parse field[%d]: %s -> fieldName = %s typeName = %s", i, s, f.fieldName, f.typeName)
 		fs[i] = f
 	}
-	return fs
+	names := map[string]bool{}
+	for _, f := range fs {
+		names[f.fieldName] = true
+	}
+	for _, f := range fs {
+		for _, a := range f.aliases() {
+			if names[a] {
+				return nil, fmt.Errorf("alias %s of field %s is duplicated", a, f.fieldName)
+			}
+			names[a] = true
+		}
+	}
+	return fs, nil
 }
 
 type configField struct {
 	typeName  string
 	fieldName string
+	typeExpr  ast.Expr
+	tags      map[string]string
+}
+
+func (s *configField) isSecret() bool {
+	_, ok := s.tags["secret"]
+	return ok
+}
+
+func (s *configField) doc() string { return s.tags["doc"] }
+
+// defaultValue returns the Go expression of the default value.
+func (s *configField) defaultValue() (string, bool) {
+	v, ok := s.tags["default"]
+	return v, ok
+}
+
+// enum returns the allowed values of the field.
+func (s *configField) enum() ([]string, bool) {
+	v, ok := s.tags["enum"]
+	if !ok {
+		return nil, false
+	}
+	xs := strings.Split(v, ",")
+	for i, x := range xs {
+		xs[i] = strings.TrimSpace(x)
+	}
+	return xs, true
+}
+
+// deprecated returns the deprecation message of the field.
+func (s *configField) deprecated() (string, bool) {
+	v, ok := s.tags["deprecated"]
+	return v, ok
+}
+
+// aliases returns the old names of the field.
+func (s *configField) aliases() []string {
+	v, ok := s.tags["alias"]
+	if !ok {
+		return nil
+	}
+	xs := strings.Split(v, ",")
+	for i, x := range xs {
+		xs[i] = capitalize(strings.TrimSpace(x))
+	}
+	return xs
+}
+
+// writeDoc writes the doc of the field as comments.
+func (s *configField) writeDoc(b *stringBuilder) {
+	d := s.doc()
+	if d != "" {
+		for _, x := range strings.Split(d, "\n") {
+			b.writef("// %s", x)
+		}
+	}
+	if v, ok := s.deprecated(); ok {
+		if d != "" {
+			b.write("//")
+		}
+		b.writef("// Deprecated: %s", v)
+	}
+}
+
+// spec returns the field in the syntax of -field.
+func (s *configField) spec() string {
+	keys := make([]string, 0, len(s.tags))
+	for k := range s.tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	xs := []string{s.fieldName, s.typeName}
+	for _, k := range keys {
+		switch knownFieldTags[k] {
+		case fieldTagFlag:
+			xs = append(xs, "@"+k)
+		case fieldTagText:
+			xs = append(xs, fmt.Sprintf("@%s=%s", k, strconv.Quote(s.tags[k])))
+		default:
+			xs = append(xs, fmt.Sprintf("@%s=%s", k, s.tags[k]))
+		}
+	}
+	return strings.Join(xs, " ")
+}
+
+// writeAliasDoc writes the deprecation of the alias as comments, newName is the name to use instead.
+func writeAliasDoc(b *stringBuilder, newName string) {
+	b.writef("// Deprecated: use %s instead.", newName)
 }
 
 type config struct {
 	typeName   string
 	configItem *configItem
 	fields     []*configField
+	freeze     *configFreeze
+	// accessor is true if the items are unexported and the accessors are generated.
+	accessor bool
+	// types is the package of the config to resolve the named types in the schema, nil if not loaded.
+	types *types.Package
 }
 
-func (s *config) generate() string {
-	var b stringBuilder
-	b.writef("type %s struct {", s.typeName)
+// itemName returns the name of the field of the config that has the item.
+func (s *config) itemName(f *configField) string {
+	if s.accessor {
+		return unexportedName(f.fieldName, "Item")
+	}
+	return f.fieldName
+}
+
+// hasDeprecation returns true if any fields are deprecated or have aliases.
+func (s *config) hasDeprecation() bool {
 	for _, f := range s.fields {
-		t := fmt.Sprintf("*%s[%s]", s.configItem.typeName, f.typeName) // config item type is generic
-		b.writef("%s %s", f.fieldName, t)
+		if _, ok := f.deprecated(); ok || len(f.aliases()) > 0 {
+			return true
+		}
 	}
-	b.write("}") // struct
-	return b.String()
+	return false
+}
+
+// hasAlias returns true if any fields have aliases.
+func (s *config) hasAlias() bool {
+	for _, f := range s.fields {
+		if len(f.aliases()) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// hasOpaqueType returns true if any fields have the types copied by reflection, see isOpaqueType.
+func (s *config) hasOpaqueType() bool {
+	for _, f := range s.fields {
+		if hasOpaqueType(f.typeExpr) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *config) hasSecret() bool {
+	for _, f := range s.fields {
+		if f.isSecret() {
+			return true
+		}
+	}
+	return false
 }
 
 type configOption struct {
@@ -362,76 +1388,973 @@ type configOption struct {
 	config   *config
 }
 
-func (s *configOption) generateConfigApply() string {
-	return fmt.Sprintf(`func (s *%[1]s) Apply(opt ...%[2]s) {
-  for _, x := range opt {
-    x(s)
-  }
-}`, s.config.typeName, s.typeName)
+type configBuilder struct {
+	typeName    string
+	constructor string
+	config      *config
 }
 
-func (s *configOption) generate() string {
+func (s *configBuilder) fieldName(i int) string {
+	return unexportedName(s.config.fields[i].fieldName, "Value")
+}
+
+type configFreeze struct {
+	tokenType string
+	errName   string
+	config    *config
+	option    *configOption
+}
+
+func (s *configFreeze) generateSetFrozen() string {
 	var b stringBuilder
-	b.write(s.generateConfigApply())
-	b.writef("type %s func(*%s)", s.typeName, s.config.typeName)
+	b.writef("func (s *%s) setFrozen(v bool) {", s.config.typeName)
 	for _, f := range s.config.fields {
-		withSig := fmt.Sprintf("func With%s(v %s) %s", f.fieldName, f.typeName, s.typeName)
-		b.writef(`%[1]s {
-  return func(c *%[2]s) {
-    c.%[3]s.Set(v)
+		b.writef("s.%s.frozen = v", s.config.itemName(f))
+	}
+	b.write("}")
+	return b.String()
+}
+
+func (s *configFreeze) generate() string {
+	var b stringBuilder
+	b.writef(`// %[3]s is returned when a frozen %[1]s is modified.
+var %[3]s = errors.New("config is frozen")
+// %[2]s allows the holder to unfreeze the %[1]s frozen by Freeze.
+type %[2]s struct {
+  c *%[1]s
+}
+// Freeze makes the config read-only.
+// After that, Set of the items and Apply panic, TrySet and TryApply return %[3]s.
+// Freeze returns the token to unfreeze the config, or nil if the config is already frozen.
+func (s *%[1]s) Freeze() *%[2]s {
+  if s.unfreezeToken != nil {
+    return nil
+  }
+  s.unfreezeToken = &%[2]s{c: s}
+  s.setFrozen(true)
+  return s.unfreezeToken
+}
+func (s *%[1]s) IsFrozen() bool {
+  return s.unfreezeToken != nil
+}
+// Unfreeze makes the config writable again.
+// Unfreeze reports false if the token is no longer valid.
+func (t *%[2]s) Unfreeze() bool {
+  if t == nil || t.c.unfreezeToken != t {
+    return false
+  }
+  t.c.unfreezeToken = nil
+  t.c.setFrozen(false)
+  return true
+}`, s.config.typeName, s.tokenType, s.errName)
+	b.write(s.generateSetFrozen())
+	if s.option != nil {
+		b.writef(`// TryApply is like Apply but returns %[3]s instead of panicking when the config is frozen.
+func (s *%[1]s) TryApply(opt ...%[2]s) error {
+  if s.IsFrozen() {
+    return %[3]s
   }
-}`, withSig, s.config.typeName, f.fieldName)
+  s.Apply(opt...)
+  return nil
+}`, s.config.typeName, s.option.typeName, s.errName)
 	}
 	return b.String()
 }
 
-type configBuilder struct {
-	typeName    string
-	constructor string
-	config      *config
+type configTx struct {
+	config *config
+	option *configOption
+	freeze *configFreeze
 }
 
-func (s *configBuilder) fieldName(i int) string {
-	return decapitalize(s.config.fields[i].fieldName)
+func (s *configTx) generate() string {
+	var b stringBuilder
+	b.writef(`// ApplyTx applies the options to a copy of the config and commits the copy all-or-nothing.
+// If the config has Validate() error method, the copy is committed only if it is valid.
+// ApplyTx returns the names of the changed fields.
+func (s *%[1]s) ApplyTx(opt ...%[2]s) ([]string, error) {`, s.config.typeName, s.option.typeName)
+	if s.freeze != nil {
+		b.writef(`if s.IsFrozen() {
+  return nil, %s
+}`, s.freeze.errName)
+	}
+	b.write(`staged := s.clone()
+staged.Apply(opt...)
+if v, ok := any(staged).(interface{ Validate() error }); ok {
+  if err := v.Validate(); err != nil {
+    return nil, err
+  }
+}
+var changed []string`)
+	for _, f := range s.config.fields {
+		b.writef(`if !s.%[1]s.equal(staged.%[1]s) {
+  *s.%[1]s = *staged.%[1]s
+  changed = append(changed, %[2]q)
+}`, s.config.itemName(f), f.fieldName)
+	}
+	b.write("return changed, nil")
+	b.write("}")
+	return b.String()
 }
 
-func (s *configBuilder) generateConstructor() string {
-	return fmt.Sprintf(`func %[1]s() *%[2]s { return &%[2]s{} }`, s.constructor, s.typeName)
+type configClone struct {
+	config   *config
+	diffType string
+	public   bool
 }
 
-func (s *configBuilder) generateType() string {
+func (s *configClone) generateClone() string {
 	var b stringBuilder
-	b.writef("type %s struct {", s.typeName)
-	for i, f := range s.config.fields {
-		b.writef("%s %s", s.fieldName(i), f.typeName)
+	b.writef("func (s *%s) clone() *%s {", s.config.typeName, s.config.typeName)
+	b.writef("return &%s{", s.config.typeName)
+	for _, f := range s.config.fields {
+		copyValue := "nil"
+		if isOpaqueType(f.typeExpr) {
+			copyValue = fmt.Sprintf("%s[%s]", s.config.configItem.deepCopyFunc(), f.typeName)
+		} else if needsDeepCopy(f.typeExpr) {
+			var c stringBuilder
+			c.writef("func(v %[1]s) (r %[1]s) {", f.typeName)
+			writeDeepCopy(&c, f.typeExpr, "r", "v", s.config.configItem.deepCopyFunc(), 1)
+			c.write("return")
+			c.WriteString("}")
+			copyValue = c.String()
+		}
+		b.writef("%[1]s: s.%[1]s.clone(%[2]s),", s.config.itemName(f), copyValue)
+	}
+	b.write("}") // return
+	b.write("}")
+	return b.String()
+}
+
+func (s *configClone) generateDiff() string {
+	var b stringBuilder
+	b.writef(`// %[1]s is a difference of a field between configs.
+type %[1]s struct {
+  Field string
+  Old any
+  New any
+  OldModified bool
+  NewModified bool
+}
+// Diff returns the fields whose items are different from other.`, s.diffType)
+	b.writef("func (s *%[1]s) Diff(other *%[1]s) []%[2]s {", s.config.typeName, s.diffType)
+	b.writef("var r []%s", s.diffType)
+	for _, f := range s.config.fields {
+		oldValue := fmt.Sprintf("s.%s.Get()", s.config.itemName(f))
+		newValue := fmt.Sprintf("other.%s.Get()", s.config.itemName(f))
+		if f.isSecret() {
+			oldValue = fmt.Sprintf("%q", redacted)
+			newValue = oldValue
+		}
+		b.writef(`if !s.%[1]s.equal(other.%[1]s) {
+  r = append(r, %[2]s{
+    Field: %[5]q,
+    Old: %[3]s,
+    New: %[4]s,
+    OldModified: s.%[1]s.modified,
+    NewModified: other.%[1]s.modified,
+  })
+}`, s.config.itemName(f), s.diffType, oldValue, newValue, f.fieldName)
+	}
+	b.write("return r")
+	b.write("}")
+	return b.String()
+}
+
+func (s *configClone) generateEqual() string {
+	var b stringBuilder
+	b.write("// Equal reports whether all items of the configs have the same values, defaults and modified states.")
+	b.write("// Funcs are compared only by whether they are nil.")
+	b.writef("func (s *%[1]s) Equal(other *%[1]s) bool {", s.config.typeName)
+	if len(s.config.fields) == 0 {
+		b.write("return true")
+	} else {
+		xs := make([]string, len(s.config.fields))
+		for i, f := range s.config.fields {
+			xs[i] = fmt.Sprintf("s.%[1]s.equal(other.%[1]s)", s.config.itemName(f))
+		}
+		b.writef("return %s", strings.Join(xs, " &&\n"))
+	}
+	b.write("}")
+	return b.String()
+}
+
+func (s *configClone) generate() string {
+	var b stringBuilder
+	b.write(s.generateClone())
+	if s.public {
+		b.writef(`// Clone returns a deep copy of the config.
+// Slices and maps in the items are copied, the clone is not frozen.
+func (s *%[1]s) Clone() *%[1]s {
+  return s.clone()
+}`, s.config.typeName)
+		b.write(s.generateEqual())
+		b.write(s.generateDiff())
 	}
-	b.write("}") // struct
 	return b.String()
 }
 
-func (s *configBuilder) generateMethods() string {
+// needsDeepCopy reports whether the type contains slices or maps to be copied, or the opaque types that may contain them.
+func needsDeepCopy(typ ast.Expr) bool {
+	switch t := typ.(type) {
+	case *ast.ParenExpr:
+		return needsDeepCopy(t.X)
+	case *ast.ArrayType:
+		if t.Len == nil { // slice
+			return true
+		}
+		return needsDeepCopy(t.Elt)
+	case *ast.MapType:
+		return true
+	default:
+		return isOpaqueType(typ)
+	}
+}
+
+// isOpaqueType reports whether the generator cannot see if the type has slices or maps,
+// e.g. the named types like http.Header and the struct types.
+// The values of them are copied by reflection.
+func isOpaqueType(typ ast.Expr) bool {
+	switch t := typ.(type) {
+	case *ast.ParenExpr:
+		return isOpaqueType(t.X)
+	case *ast.Ident:
+		return types.Universe.Lookup(t.Name) == nil
+	case *ast.SelectorExpr, *ast.IndexExpr, *ast.IndexListExpr, *ast.StructType:
+		return true
+	default:
+		return false
+	}
+}
+
+// hasOpaqueType reports whether the type is or contains the opaque types to be copied.
+func hasOpaqueType(typ ast.Expr) bool {
+	switch t := typ.(type) {
+	case *ast.ParenExpr:
+		return hasOpaqueType(t.X)
+	case *ast.ArrayType:
+		return hasOpaqueType(t.Elt)
+	case *ast.MapType:
+		return hasOpaqueType(t.Value)
+	default:
+		return isOpaqueType(typ)
+	}
+}
+
+// deepCopyFunc returns the generic function that copies the values of the opaque types.
+func (s *configItem) deepCopyFunc() string {
+	return fmt.Sprintf("copy%sValue", s.typeName)
+}
+
+// writeDeepCopy writes statements that copy src into dst, copying slices and maps recursively.
+// The values of the opaque types are copied by copyFunc.
+// depth makes the names of the loop variables unique.
+func writeDeepCopy(b *stringBuilder, typ ast.Expr, dst, src, copyFunc string, depth int) {
+	if isOpaqueType(typ) {
+		b.writef("%s = %s(%s)", dst, copyFunc, src)
+		return
+	}
+	switch t := typ.(type) {
+	case *ast.ParenExpr:
+		writeDeepCopy(b, t.X, dst, src, copyFunc, depth)
+	case *ast.ArrayType:
+		if t.Len != nil { // array
+			b.writef("%s = %s", dst, src)
+			if needsDeepCopy(t.Elt) {
+				b.writef("for i%[1]d := range %[2]s {", depth, src)
+				writeDeepCopy(b, t.Elt, fmt.Sprintf("%s[i%d]", dst, depth), fmt.Sprintf("%s[i%d]", src, depth), copyFunc, depth+1)
+				b.write("}")
+			}
+			return
+		}
+		b.writef("if %s != nil {", src)
+		b.writef("%s = make(%s, len(%s))", dst, types.ExprString(t), src)
+		if needsDeepCopy(t.Elt) {
+			b.writef("for i%[1]d := range %[2]s {", depth, src)
+			writeDeepCopy(b, t.Elt, fmt.Sprintf("%s[i%d]", dst, depth), fmt.Sprintf("%s[i%d]", src, depth), copyFunc, depth+1)
+			b.write("}")
+		} else {
+			b.writef("copy(%s, %s)", dst, src)
+		}
+		b.write("}")
+	case *ast.MapType:
+		b.writef("if %s != nil {", src)
+		b.writef("%s = make(%s, len(%s))", dst, types.ExprString(t), src)
+		b.writef("for k%[1]d, v%[1]d := range %[2]s {", depth, src)
+		if needsDeepCopy(t.Value) {
+			b.writef("var x%d %s", depth, types.ExprString(t.Value))
+			writeDeepCopy(b, t.Value, fmt.Sprintf("x%d", depth), fmt.Sprintf("v%d", depth), copyFunc, depth+1)
+			b.writef("%[1]s[k%[2]d] = x%[2]d", dst, depth)
+		} else {
+			b.writef("%[1]s[k%[2]d] = v%[2]d", dst, depth)
+		}
+		b.write("}")
+		b.write("}")
+	default:
+		b.writef("%s = %s", dst, src)
+	}
+}
+
+type configReset struct {
+	config *config
+	freeze *configFreeze
+}
+
+func (s *configReset) generate() string {
 	var b stringBuilder
+	b.write("// ResetAll resets all items of the config.")
+	b.writef("func (s *%s) ResetAll() {", s.config.typeName)
+	for _, f := range s.config.fields {
+		b.writef("s.%s.Reset()", s.config.itemName(f))
+	}
+	b.write("}")
+	b.write(`// Reset resets the items of the fields.
+// Reset returns an error without resetting any items if unknown field is given.`)
+	if s.freeze != nil {
+		b.writef("// Reset returns %s if the config is frozen, while ResetAll panics.", s.freeze.errName)
+	}
+	b.writef("func (s *%s) Reset(fieldName ...string) error {", s.config.typeName)
+	if s.freeze != nil {
+		b.writef(`if s.IsFrozen() {
+  return %s
+}`, s.freeze.errName)
+	}
+	b.write("for _, x := range fieldName {")
+	b.write("switch x {")
+	b.write(s.generateFieldCases())
+	b.write(`default:
+  return fmt.Errorf("unknown field: %s", x)
+}`) // switch
+	b.write("}") // for
+	b.write("for _, x := range fieldName {")
+	b.write("switch x {")
+	for _, f := range s.config.fields {
+		b.writef(`case %q:
+  s.%s.Reset()`, f.fieldName, s.config.itemName(f))
+	}
+	b.write("}") // switch
+	b.write("}") // for
+	b.write("return nil")
+	b.write("}")
+	b.write("// ModifiedFields returns the names of the fields whose items are modified.")
+	b.writef("func (s *%s) ModifiedFields() []string {", s.config.typeName)
+	b.write("var r []string")
+	for _, f := range s.config.fields {
+		b.writef(`if s.%s.IsModified() {
+  r = append(r, %q)
+}`, s.config.itemName(f), f.fieldName)
+	}
+	b.write("return r")
+	b.write("}")
+	return b.String()
+}
+
+func (s *configReset) generateFieldCases() string {
+	xs := make([]string, len(s.config.fields))
 	for i, f := range s.config.fields {
-		b.writef(`func (s *%[1]s) %[2]s(v %[3]s) *%[1]s {
-  s.%[4]s = v
-  return s
-}`, s.typeName, f.fieldName, f.typeName, s.fieldName(i))
+		xs[i] = fmt.Sprintf("%q", f.fieldName)
 	}
-	// Build()
-	b.writef("func (s *%s) Build() *%s {", s.typeName, s.config.typeName)
-	b.writef("return &%s{", s.config.typeName)
+	return fmt.Sprintf("case %s:", strings.Join(xs, ", "))
+}
+
+type configValues struct {
+	typeName string
+	config   *config
+	builder  *configBuilder
+	option   *configOption
+}
+
+func (s *configValues) generateToBuilder() string {
+	var b stringBuilder
+	b.writef(`// ToBuilder returns a builder seeded with the defaults of the config.
+func (s *%s) ToBuilder() *%s {`, s.config.typeName, s.builder.typeName)
+	b.writef("return &%s{", s.builder.typeName)
 	for i, f := range s.config.fields {
-		b.writef("%s: %s(s.%s),", f.fieldName, s.config.configItem.constructor, s.fieldName(i))
+		b.writef("%s: s.%s.Default(),", s.builder.fieldName(i), s.config.itemName(f))
 	}
 	b.write("}") // return
 	b.write("}")
 	return b.String()
 }
 
-func (s *configBuilder) generate() string {
+func (s *configValues) generateValues() string {
+	var b stringBuilder
+	b.writef("// %s is a snapshot of the values of %s.", s.typeName, s.config.typeName)
+	b.writef("type %s struct {", s.typeName)
+	for _, f := range s.config.fields {
+		b.writef("%s %s", f.fieldName, f.typeName)
+	}
+	b.write("}") // struct
+	b.write(`// Values returns the current values of the config.
+// Slices and maps in the values are copied.`)
+	b.writef("func (s *%s) Values() %s {", s.config.typeName, s.typeName)
+	b.writef("var r %s", s.typeName)
+	for _, f := range s.config.fields {
+		if !needsDeepCopy(f.typeExpr) {
+			b.writef("r.%s = s.%s.Get()", f.fieldName, s.config.itemName(f))
+			continue
+		}
+		b.write("{")
+		b.writef("v := s.%s.Get()", s.config.itemName(f))
+		writeDeepCopy(&b, f.typeExpr, fmt.Sprintf("r.%s", f.fieldName), "v", s.config.configItem.deepCopyFunc(), 1)
+		b.write("}")
+	}
+	b.write("return r")
+	b.write("}")
+	return b.String()
+}
+
+func (s *configValues) generateRedaction() string {
+	var (
+		b        stringBuilder
+		formats  = make([]string, len(s.config.fields))
+		goFormat = make([]string, len(s.config.fields))
+		args     []string
+		attrs    = make([]string, len(s.config.fields))
+	)
+	for i, f := range s.config.fields {
+		if f.isSecret() {
+			formats[i] = fmt.Sprintf("%s:%s", f.fieldName, redacted)
+			goFormat[i] = formats[i]
+			attrs[i] = fmt.Sprintf("slog.String(%q, %q),", f.fieldName, redacted)
+			continue
+		}
+		formats[i] = fmt.Sprintf("%s:%%v", f.fieldName)
+		goFormat[i] = fmt.Sprintf("%s:%%#v", f.fieldName)
+		args = append(args, fmt.Sprintf("v.%s", f.fieldName))
+		attrs[i] = fmt.Sprintf("slog.Any(%[1]q, v.%[1]s),", f.fieldName)
+	}
+	var argList string
+	if len(args) > 0 {
+		argList = ", " + strings.Join(args, ", ")
+	}
+	b.writef(`func (v %s) String() string {
+  return fmt.Sprintf(%q%s)
+}`, s.typeName, "{"+strings.Join(formats, " ")+"}", argList)
+	b.writef(`func (v %s) GoString() string {
+  return fmt.Sprintf(%q, v%s)
+}`, s.typeName, "%T{"+strings.Join(goFormat, ", ")+"}", argList)
+	b.writef(`func (v %s) LogValue() slog.Value {
+  return slog.GroupValue(
+%s
+  )
+}`, s.typeName, strings.Join(attrs, "\n"))
+	return b.String()
+}
+
+func (s *configValues) generate() string {
+	var b stringBuilder
+	b.write(s.generateToBuilder())
+	b.write(s.generateValues())
+	if s.config.hasSecret() {
+		b.write(s.generateRedaction())
+	}
+	b.write("// Apply sets all values to the config, all items of the config become modified.")
+	b.writef("func (v %s) Apply(c *%s) {", s.typeName, s.config.typeName)
+	for _, f := range s.config.fields {
+		b.writef("c.%s.Set(v.%s)", s.config.itemName(f), f.fieldName)
+	}
+	b.write("}")
+	if s.option != nil {
+		b.write("// ToOptions returns the options to set all values.")
+		b.writef("func (v %s) ToOptions() []%s {", s.typeName, s.option.typeName)
+		b.writef("return []%s{", s.option.typeName)
+		for _, f := range s.config.fields {
+			b.writef("With%[1]s(v.%[1]s),", f.fieldName)
+		}
+		b.write("}") // return
+		b.write("}")
+	}
+	return b.String()
+}
+
+type configEnv struct {
+	config      *config
+	prefix      string
+	deprecation *configDeprecation // nil if no fields are deprecated
+}
+
+// envName returns the environment variable name of the field, e.g. DBPassword to PREFIX_DB_PASSWORD.
+func (s *configEnv) envName(f *configField) string { return s.envNameOf(f.fieldName) }
+
+func (s *configEnv) envNameOf(fieldName string) string {
+	name := toUpperSnake(fieldName)
+	if s.prefix == "" {
+		return name
+	}
+	return fmt.Sprintf("%s_%s", s.prefix, name)
+}
+
+// writeLoad writes the code to set the value of the field from the environment variable name.
+func (s *configEnv) writeLoad(b *stringBuilder, f *configField, name string) {
+	if f.isSecret() {
+		b.writef(`if err := s.%[1]s.loadEnvFile(%[2]q, %[3]q); err != nil {
+  return err
+}`, s.config.itemName(f), name, name+"_FILE")
+		return
+	}
+	b.writef(`if v, ok := os.LookupEnv(%[2]q); ok {
+  if err := s.%[1]s.loadText(%[2]q, v); err != nil {
+    return err
+  }
+}`, s.config.itemName(f), name)
+}
+
+// writeWarn writes the code to warn msg if the environment variable name is set.
+func (s *configEnv) writeWarn(b *stringBuilder, f *configField, name, msg string) {
+	cond := fmt.Sprintf("os.Getenv(%q) != \"\"", name)
+	if f.isSecret() {
+		cond = fmt.Sprintf("%s || os.Getenv(%q) != \"\"", cond, name+"_FILE")
+	}
+	b.writef(`if %s {
+  %s(%q)
+}`, cond, s.deprecation.warnFunc, msg)
+}
+
+func (s *configEnv) generate() string {
+	var b stringBuilder
+	b.write(`// LoadEnv sets the values of the fields from the environment variables.
+// The unset variables are ignored.`)
+	if s.config.hasSecret() {
+		b.write(`// The values of the secret fields are also read from the files given by NAME_FILE variables,
+// whose trailing newlines are trimmed. The world-readable files are refused.`)
+	}
+	if s.deprecation != nil {
+		b.write(`// The deprecated variables, including the aliases, are warned by the deprecation logger.
+// The aliases are read before the variables of the fields, so the latter win.`)
+	}
+	b.writef("func (s *%s) LoadEnv() error {", s.config.typeName)
+	for _, f := range s.config.fields {
+		name := s.envName(f)
+		for _, a := range f.aliases() {
+			alias := s.envNameOf(a)
+			s.writeWarn(&b, f, alias, fmt.Sprintf("%s is deprecated, use %s instead", alias, name))
+			s.writeLoad(&b, f, alias)
+		}
+		if v, ok := f.deprecated(); ok {
+			s.writeWarn(&b, f, name, fmt.Sprintf("%s is deprecated: %s", name, v))
+		}
+		s.writeLoad(&b, f, name)
+	}
+	b.write("return nil")
+	b.write("}")
+	if s.config.hasSecret() {
+		b.write(s.generateLoadEnvFile())
+	}
+	return b.String()
+}
+
+func (s *configEnv) generateLoadEnvFile() string {
+	return fmt.Sprintf(`// loadEnvFile sets the value from the environment variable name or the file given by fileName variable.
+func (s *%[1]s[T]) loadEnvFile(name, fileName string) error {
+  v, ok := os.LookupEnv(name)
+  path, fileOk := os.LookupEnv(fileName)
+  switch {
+  case ok && fileOk:
+    return fmt.Errorf("both %%s and %%s are set", name, fileName)
+  case ok:
+    return s.loadText(name, v)
+  case fileOk:
+    info, err := os.Stat(path)
+    if err != nil {
+      return fmt.Errorf("%%s: %%w", fileName, err)
+    }
+    if info.Mode().Perm()&0o004 != 0 {
+      return fmt.Errorf("%%s: %%s is world-readable", fileName, path)
+    }
+    b, err := os.ReadFile(path)
+    if err != nil {
+      return fmt.Errorf("%%s: %%w", fileName, err)
+    }
+    return s.loadText(fileName, strings.TrimRight(string(b), "\r\n"))
+  default:
+    return nil
+  }
+}`, s.config.configItem.typeName)
+}
+
+// toUpperSnake converts camel case into upper snake case, e.g. HTTPServerURL to HTTP_SERVER_URL.
+func toUpperSnake(v string) string {
+	rs := []rune(v)
+	var b strings.Builder
+	for i, r := range rs {
+		if i > 0 && unicode.IsUpper(r) {
+			prev := rs[i-1]
+			nextLower := i+1 < len(rs) && unicode.IsLower(rs[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+	return b.String()
+}
+
+type configDeprecation struct {
+	loggerName string
+	warnFunc   string
+	warnedName string
+}
+
+func (s *configDeprecation) generate() string {
+	return fmt.Sprintf(`// %[1]s logs the uses of the deprecated fields and aliases by the loaders.
+// Each message is logged once. Replace it to change the destination.
+var %[1]s = func(msg string) { log.Print(msg) }
+
+var %[3]s sync.Map
+
+func %[2]s(msg string) {
+  if _, loaded := %[3]s.LoadOrStore(msg, true); !loaded {
+    %[1]s(msg)
+  }
+}
+`, s.loggerName, s.warnFunc, s.warnedName)
+}
+
+type configDir struct {
+	config      *config
+	deprecation *configDeprecation // nil if no fields are deprecated
+}
+
+// fileKeys returns the normalized file names of the field: lower case of the field name and its snake case.
+func (s *configDir) fileKeys(f *configField) []string { return fileKeysOf(f.fieldName) }
+
+func fileKeysOf(fieldName string) []string {
+	name := strings.ToLower(fieldName)
+	snake := strings.ToLower(toUpperSnake(fieldName))
+	if name == snake {
+		return []string{name}
+	}
+	return []string{name, snake}
+}
+
+// checkFileKeys returns an error if the file names of LoadDir of the fields or the aliases collide.
+func checkFileKeys(fields []*configField) error {
+	owners := map[string]string{}
+	for _, f := range fields {
+		for _, name := range append([]string{f.fieldName}, f.aliases()...) {
+			for _, k := range fileKeysOf(name) {
+				if x, ok := owners[k]; ok && x != name {
+					return fmt.Errorf("file name %s of %s collides with %s", k, name, x)
+				}
+				owners[k] = name
+			}
+		}
+	}
+	return nil
+}
+
+func quoteAll(xs []string) []string {
+	r := make([]string, len(xs))
+	for i, x := range xs {
+		r[i] = strconv.Quote(x)
+	}
+	return r
+}
+
+func (s *configDir) generate() string {
+	var b stringBuilder
+	var deprecationDoc string
+	if s.deprecation != nil {
+		deprecationDoc = `
+// The deprecated files, including the aliases, are warned by the deprecation logger.
+// The aliases are ignored if the files of the fields are given.`
+	}
+	var givenFiles string
+	if s.config.hasAlias() {
+		givenFiles = `
+  // the files of the fields win over the aliases
+  given := map[string]bool{}
+  for _, e := range entries {
+    if !e.IsDir() {
+      given[strings.ToLower(strings.ReplaceAll(e.Name(), "-", "_"))] = true
+    }
+  }`
+	}
+	b.writef(`// LoadDir sets the values of the fields from the files in dir, like a mounted ConfigMap.
+// The file names are the field names or the snake cases of them, case-insensitive, hyphens as underscores,
+// e.g. DBPassword, db_password or DB-PASSWORD. The contents are the values, whose trailing newlines are trimmed.
+// If dir has ..data, the files are read from the directory it points to, to see a consistent snapshot
+// while ..data is swapped. Hidden files and directories are ignored.
+// If strict, unknown files are errors, otherwise they are ignored.%s
+func (s *%s) LoadDir(dir string, strict bool) error {
+  if p, err := filepath.EvalSymlinks(filepath.Join(dir, "..data")); err == nil {
+    dir = p
+  }
+  entries, err := os.ReadDir(dir)
+  if err != nil {
+    return err
+  }%s
+  for _, e := range entries {
+    name := e.Name()
+    if strings.HasPrefix(name, ".") {
+      continue
+    }
+    path := filepath.Join(dir, name)
+    info, err := os.Stat(path)
+    if err != nil {
+      return err
+    }
+    if info.IsDir() {
+      continue
+    }
+    b, err := os.ReadFile(path)
+    if err != nil {
+      return err
+    }
+    v := strings.TrimRight(string(b), "\r\n")
+    switch strings.ToLower(strings.ReplaceAll(name, "-", "_")) {`, deprecationDoc, s.config.typeName, givenFiles)
+	for _, f := range s.config.fields {
+		for _, a := range f.aliases() {
+			given := make([]string, len(s.fileKeys(f)))
+			for i, k := range s.fileKeys(f) {
+				given[i] = fmt.Sprintf("given[%q]", k)
+			}
+			b.writef(`case %s:
+  %s(fmt.Sprintf("file %%s is deprecated, use %s instead", name))
+  if %s {
+    continue
+  }
+  err = s.%s.loadText(path, v)`, strings.Join(quoteAll(fileKeysOf(a)), ", "), s.deprecation.warnFunc, f.fieldName, strings.Join(given, " || "), s.config.itemName(f))
+		}
+		b.writef("case %s:", strings.Join(quoteAll(s.fileKeys(f)), ", "))
+		if v, ok := f.deprecated(); ok {
+			b.writef(`%s(fmt.Sprintf("file %%s is deprecated: %%s", name, %q))`, s.deprecation.warnFunc, v)
+		}
+		b.writef("err = s.%s.loadText(path, v)", s.config.itemName(f))
+	}
+	b.write(`default:
+  if strict {
+    return fmt.Errorf("unknown file: %s", path)
+  }
+}`) // switch
+	b.write(`if err != nil {
+  return err
+}`)
+	b.write("}") // for
+	b.write("return nil")
+	b.write("}")
+	return b.String()
+}
+
+type configJSON struct {
+	config         *config
+	schemaVersion  int
+	versionName    string
+	migrationsName string
+	registerName   string
+	deprecation    *configDeprecation // nil if no fields are deprecated
+	freeze         *configFreeze      // nil if not freeze
+}
+
+// schemaVersionKey is the key of the version of the config documents.
+const schemaVersionKey = "schemaVersion"
+
+func (s *configJSON) generate() string {
+	var b stringBuilder
+	b.writef(`// %[1]s is the version of the config documents read by LoadJSON.
+const %[1]s = %[2]d
+
+var %[3]s = map[int]func(map[string]any) error{}
+
+// %[4]s registers the migration of the config documents from the version to the next one.
+// The migration modifies the document in place, whose numbers are json.Number.
+func %[4]s(from int, migrate func(map[string]any) error) {
+  %[3]s[from] = migrate
+}`, s.versionName, s.schemaVersion, s.migrationsName, s.registerName)
+
+	b.writef(`// LoadJSON sets the values of the fields from the JSON document.
+// The keys are the field names and %[2]q, the version of the document, 1 if not present.
+// The old documents are migrated by the registered migrations before the values are set,
+// the documents newer than %[1]s are errors. Unknown keys are errors.
+// The values are set to a copy of the config, which is committed only if all of them are loaded
+// and, if the config has Validate() error method, the copy is valid.`, s.versionName, schemaVersionKey)
+	if s.deprecation != nil {
+		b.write("// The deprecated keys, including the aliases, are warned by the deprecation logger.")
+		b.write("// The aliases are ignored if the keys of the fields are given.")
+	}
+	b.writef("func (s *%s) LoadJSON(data []byte) error {", s.config.typeName)
+	if s.freeze != nil {
+		b.writef(`if s.IsFrozen() {
+  return %s
+}`, s.freeze.errName)
+	}
+	b.writef(`var doc map[string]any
+  d := json.NewDecoder(bytes.NewReader(data))
+  d.UseNumber()
+  if err := d.Decode(&doc); err != nil {
+    return err
+  }
+  if doc == nil { // null
+    doc = map[string]any{}
+  }
+  version := 1
+  if v, ok := doc[%[2]q]; ok {
+    x, ok := v.(json.Number)
+    if !ok {
+      return fmt.Errorf("invalid %[2]s: %%v", v)
+    }
+    n, err := strconv.Atoi(x.String())
+    if err != nil {
+      return fmt.Errorf("invalid %[2]s: %%w", err)
+    }
+    version = n
+    delete(doc, %[2]q)
+  }
+  if version > %[1]s {
+    return fmt.Errorf("%[2]s %%d is newer than %%d", version, %[1]s)
+  }
+  for ; version < %[1]s; version++ {
+    migrate, ok := %[3]s[version]
+    if !ok {
+      return fmt.Errorf("no migration from %[2]s %%d", version)
+    }
+    if err := migrate(doc); err != nil {
+      return fmt.Errorf("failed to migrate from %[2]s %%d: %%w", version, err)
+    }
+  }
+  staged := s.clone()
+  keys := make([]string, 0, len(doc))
+  for k := range doc {
+    keys = append(keys, k)
+  }
+  sort.Strings(keys)
+  for _, k := range keys {
+    v, err := json.Marshal(doc[k])
+    if err != nil {
+      return fmt.Errorf("%%s: %%w", k, err)
+    }
+    switch k {`, s.versionName, schemaVersionKey, s.migrationsName)
+	for _, f := range s.config.fields {
+		if _, ok := typeSchema(f.typeExpr, nil); !ok {
+			continue
+		}
+		for _, a := range f.aliases() {
+			b.writef(`case %q:
+  %s(%q)
+  if _, ok := doc[%q]; ok {
+    continue
+  }
+  err = staged.%s.loadJSON(k, v)`, a, s.deprecation.warnFunc, fmt.Sprintf("key %s is deprecated, use %s instead", a, f.fieldName), f.fieldName, s.config.itemName(f))
+		}
+		b.writef("case %q:", f.fieldName)
+		if v, ok := f.deprecated(); ok {
+			b.writef("%s(%q)", s.deprecation.warnFunc, fmt.Sprintf("key %s is deprecated: %s", f.fieldName, v))
+		}
+		b.writef("err = staged.%s.loadJSON(k, v)", s.config.itemName(f))
+	}
+	b.write(`default:
+  return fmt.Errorf("unknown key: %s", k)
+}`) // switch
+	b.write(`if err != nil {
+  return err
+}`)
+	b.write("}") // for
+	b.write(`if v, ok := any(staged).(interface{ Validate() error }); ok {
+  if err := v.Validate(); err != nil {
+    return err
+  }
+}`)
+	for _, f := range s.config.fields {
+		b.writef("*s.%[1]s = *staged.%[1]s", s.config.itemName(f))
+	}
+	b.write("return nil")
+	b.write("}")
+	return b.String()
+}
+
+type configReader struct {
+	typeName string
+	fakeName string
+	config   *config
+}
+
+// fakeFieldName returns the name of the field of the fake that has the value.
+func (s *configReader) fakeFieldName(f *configField) string {
+	return fmt.Sprintf("%sValue", f.fieldName)
+}
+
+func (s *configReader) generate() string {
+	var b stringBuilder
+	b.writef(`// %[1]s is the read-only view of %[2]s.
+type %[1]s interface {`, s.typeName, s.config.typeName)
+	for _, f := range s.config.fields {
+		f.writeDoc(&b)
+		b.writef("%s() %s", f.fieldName, f.typeName)
+	}
+	b.write("}") // interface
+	b.writef("var _ %s = (*%s)(nil)", s.typeName, s.config.typeName)
+
+	b.writef(`// %[1]s is a %[2]s whose values are set directly, for tests.
+type %[1]s struct {`, s.fakeName, s.typeName)
+	for _, f := range s.config.fields {
+		b.writef("%s %s", s.fakeFieldName(f), f.typeName)
+	}
+	b.write("}") // struct
+	b.writef("var _ %s = (*%s)(nil)", s.typeName, s.fakeName)
+	for _, f := range s.config.fields {
+		b.writef("func (s *%s) %s() %s { return s.%s }", s.fakeName, f.fieldName, f.typeName, s.fakeFieldName(f))
+	}
+	return b.String()
+}
+
+type configOverride struct {
+	config *config
+	option *configOption
+}
+
+// generate generates Override.
+// It takes the subset of testing.TB so that the config does not import testing.
+func (s *configOverride) generate() string {
 	var b stringBuilder
-	b.write(s.generateType())
-	b.write(s.generateMethods())
-	b.write(s.generateConstructor())
+	b.writef(`// Override applies the options to the config and restores the items when the test finishes.
+// t is usually testing.TB.
+func (s *%s) Override(t interface {
+  Helper()
+  Cleanup(func())
+}, opt ...%s) {
+t.Helper()`, s.config.typeName, s.option.typeName)
+	for _, f := range s.config.fields {
+		b.writef(`{
+  v := *s.%[1]s
+  t.Cleanup(func() { *s.%[1]s = v })
+}`, s.config.itemName(f))
+	}
+	b.write("s.Apply(opt...)")
+	b.write("}")
 	return b.String()
 }
+
+type configContext struct {
+	keyType string
+	config  *config
+	option  *configOption
+}
+
+func (s *configContext) generate() string {
+	return fmt.Sprintf(`type %[1]s struct{}
+
+// New%[2]sContext returns a copy of ctx that carries the config.
+func New%[2]sContext(ctx context.Context, c *%[2]s) context.Context {
+  return context.WithValue(ctx, %[1]s{}, c)
+}
+
+// %[2]sFromContext returns the config carried by ctx.
+func %[2]sFromContext(ctx context.Context) (*%[2]s, bool) {
+  c, ok := ctx.Value(%[1]s{}).(*%[2]s)
+  return c, ok && c != nil
+}
+
+// With%[2]sOverrides returns a copy of ctx that carries a clone of the config of ctx with the options applied.
+// The config of ctx is not modified. ctx is returned as is if it does not carry the config.
+func With%[2]sOverrides(ctx context.Context, opt ...%[3]s) context.Context {
+  c, ok := %[2]sFromContext(ctx)
+  if !ok {
+    return ctx
+  }
+  c = c.clone()
+  c.Apply(opt...)
+  return New%[2]sContext(ctx, c)
+}
+`, s.keyType, s.config.typeName, s.option.typeName)
+}
@@ -0,0 +1,330 @@
+package main
+
+import (
+	"go/parser"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseConfigField(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		field string
+		want  *configField
+		err   bool
+	}{
+		{
+			name:  "no tags",
+			field: "size int",
+			want: &configField{
+				fieldName: "Size",
+				typeName:  "int",
+			},
+		},
+		{
+			name:  "func type",
+			field: "f func(int) (string, error)",
+			want: &configField{
+				fieldName: "F",
+				typeName:  "func(int) (string, error)",
+			},
+		},
+		{
+			name:  "secret",
+			field: "Password string @secret",
+			want: &configField{
+				fieldName: "Password",
+				typeName:  "string",
+				tags: map[string]string{
+					"secret": "",
+				},
+			},
+		},
+		{
+			name:  "doc and default",
+			field: `Name string @default="x @y" @doc="the \"name\""`,
+			want: &configField{
+				fieldName: "Name",
+				typeName:  "string",
+				tags: map[string]string{
+					"default": `"x @y"`,
+					"doc":     `the "name"`,
+				},
+			},
+		},
+		{
+			name:  "unquoted doc and composite default",
+			field: `Names []string @doc=the names @default=[]string{"a", "b"} @secret`,
+			want: &configField{
+				fieldName: "Names",
+				typeName:  "[]string",
+				tags: map[string]string{
+					"default": `[]string{"a", "b"}`,
+					"doc":     "the names",
+					"secret":  "",
+				},
+			},
+		},
+		{
+			name:  "constraints",
+			field: `Level string @min=1 @max=16 @enum="debug,info"`,
+			want: &configField{
+				fieldName: "Level",
+				typeName:  "string",
+				tags: map[string]string{
+					"min":  "1",
+					"max":  "16",
+					"enum": "debug,info",
+				},
+			},
+		},
+		{
+			name:  "invalid min",
+			field: "Size int @min=one",
+			err:   true,
+		},
+		{
+			name:  "deprecated and aliases",
+			field: `Size int @deprecated="use Length" @alias=OldSize,length`,
+			want: &configField{
+				fieldName: "Size",
+				typeName:  "int",
+				tags: map[string]string{
+					"deprecated": "use Length",
+					"alias":      "OldSize,length",
+				},
+			},
+		},
+		{
+			name:  "invalid alias",
+			field: "Size int @alias=old-size",
+			err:   true,
+		},
+		{
+			name:  "invalid default",
+			field: "Size int @default=(",
+			err:   true,
+		},
+		{
+			name:  "doc without value",
+			field: "Size int @doc",
+			err:   true,
+		},
+		{
+			name:  "unknown tag",
+			field: "Password string @unknown",
+			err:   true,
+		},
+		{
+			name:  "duplicated tag",
+			field: "Password string @secret @secret",
+			err:   true,
+		},
+		{
+			name:  "value for flag tag",
+			field: "Password string @secret=true",
+			err:   true,
+		},
+		{
+			name:  "no type",
+			field: "Password @secret",
+			err:   true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseConfigField(tc.field)
+			if tc.err {
+				assert.NotNil(t, err)
+				return
+			}
+			if !assert.Nil(t, err) {
+				return
+			}
+			assert.Equal(t, tc.want.fieldName, got.fieldName)
+			assert.Equal(t, tc.want.typeName, got.typeName)
+			assert.Equal(t, tc.want.tags, got.tags)
+		})
+	}
+}
+
+func TestToUpperSnake(t *testing.T) {
+	for _, tc := range []struct {
+		v    string
+		want string
+	}{
+		{v: "Size", want: "SIZE"},
+		{v: "ErrorHandling", want: "ERROR_HANDLING"},
+		{v: "DBPassword", want: "DB_PASSWORD"},
+		{v: "HTTPServerURL", want: "HTTP_SERVER_URL"},
+		{v: "V2Name", want: "V2_NAME"},
+		{v: "ID", want: "ID"},
+	} {
+		t.Run(tc.v, func(t *testing.T) {
+			assert.Equal(t, tc.want, toUpperSnake(tc.v))
+		})
+	}
+}
+
+func TestSplitConfigFields(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		fields string
+		want   []string
+	}{
+		{
+			name:   "one",
+			fields: "A int",
+			want:   []string{"A int"},
+		},
+		{
+			name:   "two",
+			fields: "A int|B string",
+			want:   []string{"A int", "B string"},
+		},
+		{
+			name:   "brackets",
+			fields: `A int @default=(1|2)|B []int @default=[]int{1|2}|C func(int) error`,
+			want:   []string{`A int @default=(1|2)`, `B []int @default=[]int{1|2}`, `C func(int) error`},
+		},
+		{
+			name:   "quoted",
+			fields: `A int @doc="a|b"|B string @doc="\"|\""`,
+			want:   []string{`A int @doc="a|b"`, `B string @doc="\"|\""`},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, splitConfigFields(tc.fields))
+		})
+	}
+}
+
+func TestIsOpaqueType(t *testing.T) {
+	for _, tc := range []struct {
+		typ  string
+		want bool
+	}{
+		{typ: "int", want: false},
+		{typ: "error", want: false},
+		{typ: "[]string", want: false},
+		{typ: "map[string]int", want: false},
+		{typ: "*Tags", want: false},
+		{typ: "func()", want: false},
+		{typ: "Tags", want: true},
+		{typ: "http.Header", want: true},
+		{typ: "List[int]", want: true},
+		{typ: "struct{ X []int }", want: true},
+	} {
+		t.Run(tc.typ, func(t *testing.T) {
+			x, err := parser.ParseExpr(tc.typ)
+			if !assert.Nil(t, err) {
+				return
+			}
+			assert.Equal(t, tc.want, isOpaqueType(x))
+		})
+	}
+}
+
+func TestSectionFilename(t *testing.T) {
+	for _, tc := range []struct {
+		dest    string
+		section string
+		want    string
+	}{
+		{dest: "config.go", section: "", want: "config.go"},
+		{dest: "config.go", section: "item", want: "config_item.go"},
+		{dest: "pkg/settings.go", section: "builder", want: "pkg/settings_builder.go"},
+		{dest: "config.go", section: "linux_plugin", want: "config_linux_plugin.go"},
+	} {
+		t.Run(tc.want, func(t *testing.T) {
+			assert.Equal(t, tc.want, sectionFilename(tc.dest, tc.section))
+		})
+	}
+}
+
+func TestSectionNames(t *testing.T) {
+	g := newGenerator("Size int @alias=Length", "Config", "Item", "Builder", "Option", generatorOptions{
+		option:        true,
+		freeze:        true,
+		tx:            true,
+		clone:         true,
+		reset:         true,
+		values:        true,
+		env:           true,
+		dir:           true,
+		accessor:      true,
+		reader:        true,
+		override:      true,
+		context:       true,
+		schemaVersion: 1,
+	})
+	assert.Nil(t, g.generate())
+	names, _ := g.splitSources()
+	assert.Equal(t, append([]string{""}, sectionNames...), names, "staleSectionFiles knows all the sections")
+}
+
+func TestKeywordFieldNames(t *testing.T) {
+	const fields = `Type string|Range int @default=1|Func bool|Map map[string]int|Go string|Select string|Var string|Default string|Chan string`
+	for _, tc := range []struct {
+		name string
+		opts generatorOptions
+	}{
+		{
+			name: "default",
+			opts: generatorOptions{
+				option: true,
+			},
+		},
+		{
+			name: "all",
+			opts: generatorOptions{
+				option:        true,
+				freeze:        true,
+				tx:            true,
+				clone:         true,
+				reset:         true,
+				values:        true,
+				env:           true,
+				dir:           true,
+				accessor:      true,
+				reader:        true,
+				override:      true,
+				context:       true,
+				schemaVersion: 1,
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			g := newGenerator(fields, "Config", "Item", "Builder", "Option", tc.opts)
+			g.Print("package p\n\n")
+			assert.Nil(t, g.generate())
+			_, err := formatSource(g.bytes(), "config.go")
+			assert.Nil(t, err)
+		})
+	}
+}
+
+func TestCheckFileKeys(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		fields string
+		ok     bool
+	}{
+		{name: "alias", fields: "Size int @alias=OldSize|Name string", ok: true},
+		{name: "alias collides with field", fields: "Size int @alias=Old_Size|OldSize int"},
+		{name: "fields collide", fields: "DBPassword string|DbPassword string"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			fields, err := parseConfigFieldList(tc.fields)
+			if !assert.Nil(t, err) {
+				return
+			}
+			err = checkFileKeys(fields)
+			if tc.ok {
+				assert.Nil(t, err)
+			} else {
+				assert.NotNil(t, err)
+			}
+		})
+	}
+}
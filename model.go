@@ -0,0 +1,212 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"strings"
+)
+
+// specModel is the spec of the config in JSON: the type names, the options and the fields.
+// It is also the input of the plugins.
+type specModel struct {
+	// Package is the name of the package of the generated code, empty unless generating.
+	Package string `json:"package,omitempty"`
+	// Output is the path of the generated code, set by -emit-model.
+	Output        string       `json:"output,omitempty"`
+	Config        string       `json:"config"`
+	ConfigItem    string       `json:"configItem"`
+	ConfigBuilder string       `json:"configBuilder"`
+	ConfigOption  string       `json:"configOption"`
+	Options       modelOptions `json:"options"`
+	Fields        []modelField `json:"fields"`
+}
+
+type modelOptions struct {
+	Option        bool   `json:"option,omitempty"`
+	Freeze        bool   `json:"freeze,omitempty"`
+	Tx            bool   `json:"tx,omitempty"`
+	Clone         bool   `json:"clone,omitempty"`
+	Reset         bool   `json:"reset,omitempty"`
+	Values        bool   `json:"values,omitempty"`
+	Env           bool   `json:"env,omitempty"`
+	EnvPrefix     string `json:"envPrefix,omitempty"`
+	Dir           bool   `json:"dir,omitempty"`
+	Accessor      bool   `json:"accessor,omitempty"`
+	Reader        bool   `json:"reader,omitempty"`
+	Override      bool   `json:"override,omitempty"`
+	Context       bool   `json:"context,omitempty"`
+	SchemaVersion int    `json:"schemaVersion,omitempty"`
+}
+
+type modelField struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	// Default is @default.
+	Default string `json:"default,omitempty"`
+	// Tags are the tags without @ except default, the values of the flag tags are empty.
+	Tags map[string]string `json:"tags,omitempty"`
+}
+
+func newModelOptions(opts generatorOptions) modelOptions {
+	return modelOptions{
+		Option:        opts.option,
+		Freeze:        opts.freeze,
+		Tx:            opts.tx,
+		Clone:         opts.clone,
+		Reset:         opts.reset,
+		Values:        opts.values,
+		Env:           opts.env,
+		EnvPrefix:     opts.envPrefix,
+		Dir:           opts.dir,
+		Accessor:      opts.accessor,
+		Reader:        opts.reader,
+		Override:      opts.override,
+		Context:       opts.context,
+		SchemaVersion: opts.schemaVersion,
+	}
+}
+
+func (s modelOptions) generatorOptions() generatorOptions {
+	return generatorOptions{
+		option:        s.Option,
+		freeze:        s.Freeze,
+		tx:            s.Tx,
+		clone:         s.Clone,
+		reset:         s.Reset,
+		values:        s.Values,
+		env:           s.Env,
+		envPrefix:     s.EnvPrefix,
+		dir:           s.Dir,
+		accessor:      s.Accessor,
+		reader:        s.Reader,
+		override:      s.Override,
+		context:       s.Context,
+		schemaVersion: s.SchemaVersion,
+	}
+}
+
+// model returns the model of the flags.
+func (s *specFlags) model() (*specModel, error) {
+	fs, err := parseConfigFieldList(*s.fields)
+	if err != nil {
+		return nil, err
+	}
+	configType, configItemType, configBuilderType, configOptionType := s.typeNames()
+	return newSpecModel(fs, configType, configItemType, configBuilderType, configOptionType, s.options()), nil
+}
+
+// model returns the model of the generator.
+func (s *generator) model() *specModel {
+	m := newSpecModel(s.conf.fields, s.conf.typeName, s.item.typeName, s.builder.typeName, s.option.typeName, s.opts)
+	m.Package = s.pkgName
+	return m
+}
+
+func newSpecModel(
+	fields []*configField,
+	configType,
+	configItemType,
+	configBuilderType,
+	configOptionType string,
+	opts generatorOptions,
+) *specModel {
+	m := &specModel{
+		Config:        configType,
+		ConfigItem:    configItemType,
+		ConfigBuilder: configBuilderType,
+		ConfigOption:  configOptionType,
+		Options:       newModelOptions(opts),
+		Fields:        make([]modelField, len(fields)),
+	}
+	for i, f := range fields {
+		v, _ := f.defaultValue()
+		tags := maps.Clone(f.tags)
+		delete(tags, "default")
+		if len(tags) == 0 {
+			tags = nil
+		}
+		m.Fields[i] = modelField{
+			Name:    f.fieldName,
+			Type:    f.typeName,
+			Default: v,
+			Tags:    tags,
+		}
+	}
+	return m
+}
+
+// fieldSpec returns the fields in the syntax of -field.
+func (s *specModel) fieldSpec() string {
+	xs := make([]string, len(s.Fields))
+	for i, f := range s.Fields {
+		tags := f.Tags
+		if f.Default != "" {
+			tags = maps.Clone(tags)
+			if tags == nil {
+				tags = map[string]string{}
+			}
+			tags["default"] = f.Default
+		}
+		xs[i] = (&configField{
+			fieldName: f.Name,
+			typeName:  f.Type,
+			tags:      tags,
+		}).spec()
+	}
+	return strings.Join(xs, "|")
+}
+
+// validate returns an error if the fields or the options are invalid.
+func (s *specModel) validate() error {
+	if len(s.Fields) == 0 {
+		return fmt.Errorf("no fields")
+	}
+	for _, f := range s.Fields {
+		if _, ok := f.Tags["default"]; ok {
+			return fmt.Errorf("field %s: the default must be given by default, not by tags", f.Name)
+		}
+	}
+	for _, x := range []struct {
+		name  string
+		value string
+	}{
+		{name: "config", value: s.Config},
+		{name: "configItem", value: s.ConfigItem},
+		{name: "configBuilder", value: s.ConfigBuilder},
+		{name: "configOption", value: s.ConfigOption},
+	} {
+		if x.value == "" {
+			return fmt.Errorf("%s must be set", x.name)
+		}
+	}
+	return validateSpec(s.fieldSpec(), s.Options.generatorOptions())
+}
+
+// newGenerator returns the generator of the model.
+func (s *specModel) newGenerator() (*generator, error) {
+	if err := s.validate(); err != nil {
+		return nil, err
+	}
+	return newGenerator(
+		s.fieldSpec(),
+		s.Config,
+		s.ConfigItem,
+		s.ConfigBuilder,
+		s.ConfigOption,
+		s.Options.generatorOptions(),
+	), nil
+}
+
+func readModel(fileName string) (*specModel, error) {
+	b, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	var m specModel
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, fmt.Errorf("%s: %w", fileName, err)
+	}
+	return &m, nil
+}
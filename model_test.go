@@ -0,0 +1,122 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpecModel(t *testing.T) {
+	t.Run("json", func(t *testing.T) {
+		g := newGenerator(`Size int @default=10 @doc="Size of the buffer."|Password string @secret`, "AppConfig", "AppConfigItem", "AppConfigBuilder", "AppConfigOption", generatorOptions{
+			option:    true,
+			env:       true,
+			envPrefix: "APP",
+		})
+		g.pkgName = "example"
+		m := g.model()
+		m.Output = "example/config.go"
+		got, err := marshalIndent(m)
+		assert.Nil(t, err)
+		assert.Equal(t, `{
+  "package": "example",
+  "output": "example/config.go",
+  "config": "AppConfig",
+  "configItem": "AppConfigItem",
+  "configBuilder": "AppConfigBuilder",
+  "configOption": "AppConfigOption",
+  "options": {
+    "option": true,
+    "env": true,
+    "envPrefix": "APP"
+  },
+  "fields": [
+    {
+      "name": "Size",
+      "type": "int",
+      "default": "10",
+      "tags": {
+        "doc": "Size of the buffer."
+      }
+    },
+    {
+      "name": "Password",
+      "type": "string",
+      "tags": {
+        "secret": ""
+      }
+    }
+  ]
+}
+`, string(got))
+	})
+
+	t.Run("default", func(t *testing.T) {
+		for _, tc := range []struct {
+			name  string
+			field modelField
+			want  string
+		}{
+			{
+				name:  "default",
+				field: modelField{Name: "Size", Type: "int", Default: "10"},
+				want:  "Size int @default=10",
+			},
+			{
+				name: "with tags",
+				field: modelField{
+					Name:    "Size",
+					Type:    "int",
+					Default: "10",
+					Tags:    map[string]string{"doc": "Size."},
+				},
+				want: `Size int @default=10 @doc="Size."`,
+			},
+		} {
+			t.Run(tc.name, func(t *testing.T) {
+				m := &specModel{Fields: []modelField{tc.field}}
+				assert.Equal(t, tc.want, m.fieldSpec())
+			})
+		}
+	})
+
+	t.Run("default in tags", func(t *testing.T) {
+		m := &specModel{
+			Config:        "Config",
+			ConfigItem:    "Item",
+			ConfigBuilder: "Builder",
+			ConfigOption:  "Option",
+			Fields: []modelField{
+				{Name: "Size", Type: "int", Tags: map[string]string{"default": "10"}},
+			},
+		}
+		assert.NotNil(t, m.validate())
+	})
+}
+
+func TestSpecModelValidate(t *testing.T) {
+	newModel := func(opts modelOptions) *specModel {
+		return &specModel{
+			Config:        "Config",
+			ConfigItem:    "Item",
+			ConfigBuilder: "Builder",
+			ConfigOption:  "Option",
+			Options:       opts,
+			Fields:        []modelField{{Name: "Size", Type: "int"}},
+		}
+	}
+	assert.Nil(t, newModel(modelOptions{Option: true, Tx: true}).validate())
+	for _, tc := range []struct {
+		name string
+		opts modelOptions
+	}{
+		{name: "tx", opts: modelOptions{Tx: true}},
+		{name: "override", opts: modelOptions{Override: true}},
+		{name: "context", opts: modelOptions{Context: true}},
+		{name: "reader", opts: modelOptions{Reader: true}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.NotNil(t, newModel(tc.opts).validate())
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// pluginPrefix is the prefix of the executables of the plugins, e.g. goconfig-gen-metrics for -plugin metrics.
+const pluginPrefix = "goconfig-gen-"
+
+// pluginResponse is the output of the plugin.
+type pluginResponse struct {
+	// Code is appended to the generated code.
+	// It is the declarations without the package clause, the imports are resolved by goimports.
+	Code string `json:"code"`
+	// Error is the error of the plugin, reported instead of the code.
+	Error string `json:"error,omitempty"`
+}
+
+// splitPlugins returns the names of the plugins of -plugin.
+func splitPlugins(v string) []string {
+	var xs []string
+	for _, x := range strings.Split(v, ",") {
+		if x = strings.TrimSpace(x); x != "" {
+			xs = append(xs, x)
+		}
+	}
+	return xs
+}
+
+// runPlugin runs goconfig-gen-name in PATH, writes the model as JSON to the stdin
+// and reads pluginResponse as JSON from the stdout.
+func runPlugin(name string, m *specModel) (string, error) {
+	path, err := exec.LookPath(pluginPrefix + name)
+	if err != nil {
+		return "", fmt.Errorf("plugin %s: %w", name, err)
+	}
+	req, err := json.Marshal(m)
+	if err != nil {
+		return "", fmt.Errorf("plugin %s: %w", name, err)
+	}
+	var stdout bytes.Buffer
+	cmd := exec.Command(path)
+	cmd.Stdin = bytes.NewReader(req)
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("plugin %s: %w", name, err)
+	}
+	var r pluginResponse
+	if err := json.Unmarshal(stdout.Bytes(), &r); err != nil {
+		return "", fmt.Errorf("plugin %s: invalid response: %w", name, err)
+	}
+	if r.Error != "" {
+		return "", fmt.Errorf("plugin %s: %s", name, r.Error)
+	}
+	if r.Code != "" && !strings.HasSuffix(r.Code, "\n") {
+		r.Code += "\n"
+	}
+	return r.Code, nil
+}
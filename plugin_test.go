@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunPlugin(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	input := filepath.Join(dir, "input.json")
+	for name, script := range map[string]string{
+		"hello":   `cat > ` + input + `; echo '{"code":"func Hello() string { return \"hello\" }"}'`,
+		"error":   `echo '{"error":"no metrics"}'`,
+		"invalid": `echo 'func Hello() {}'`,
+		"exit":    `exit 1`,
+	} {
+		p := filepath.Join(dir, pluginPrefix+name)
+		assert.Nil(t, os.WriteFile(p, []byte("#!/bin/sh\n"+script+"\n"), 0700))
+	}
+
+	g := newGenerator("Size int @default=10", "Config", "ConfigItem", "ConfigBuilder", "ConfigOption", generatorOptions{option: true})
+	g.pkgName = "example"
+
+	t.Run("code", func(t *testing.T) {
+		got, err := runPlugin("hello", g.model())
+		assert.Nil(t, err)
+		assert.Equal(t, "func Hello() string { return \"hello\" }\n", got)
+
+		b, err := os.ReadFile(input)
+		assert.Nil(t, err)
+		var req specModel
+		assert.Nil(t, json.Unmarshal(b, &req))
+		assert.Equal(t, specModel{
+			Package:       "example",
+			Config:        "Config",
+			ConfigItem:    "ConfigItem",
+			ConfigBuilder: "ConfigBuilder",
+			ConfigOption:  "ConfigOption",
+			Options:       modelOptions{Option: true},
+			Fields: []modelField{
+				{
+					Name:    "Size",
+					Type:    "int",
+					Default: "10",
+				},
+			},
+		}, req)
+	})
+
+	t.Run("generate", func(t *testing.T) {
+		g.plugins = []string{"hello"}
+		assert.Nil(t, g.generate())
+		assert.True(t, strings.HasSuffix(string(g.bytes()), "\t}\n}\nfunc Hello() string { return \"hello\" }\n"))
+	})
+
+	t.Run("generate error", func(t *testing.T) {
+		g.plugins = []string{"exit"}
+		assert.ErrorContains(t, g.generate(), "plugin exit: exit status 1")
+	})
+
+	for _, tc := range []struct {
+		name string
+		err  string
+	}{
+		{name: "error", err: "plugin error: no metrics"},
+		{name: "invalid", err: "plugin invalid: invalid response"},
+		{name: "exit", err: "plugin exit: exit status 1"},
+		{name: "missing", err: "plugin missing: "},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := runPlugin(tc.name, g.model())
+			if assert.NotNil(t, err) {
+				assert.Contains(t, err.Error(), tc.err)
+			}
+		})
+	}
+}
+
+func TestSplitPlugins(t *testing.T) {
+	assert.Nil(t, splitPlugins(""))
+	assert.Equal(t, []string{"metrics", "admin"}, splitPlugins("metrics, admin,"))
+}
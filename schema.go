@@ -0,0 +1,571 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const jsonSchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// schemaFilename returns the name of the schema file next to the generated file, e.g. config.schema.json for config.go.
+func schemaFilename(goFilename string) string {
+	return strings.TrimSuffix(goFilename, filepath.Ext(goFilename)) + ".schema.json"
+}
+
+// orderedObject is a JSON object that keeps the order of the members.
+type orderedObject []orderedMember
+
+type orderedMember struct {
+	key   string
+	value any
+}
+
+// has reports whether the key is set.
+func (s orderedObject) has(key string) bool {
+	return slices.ContainsFunc(s, func(m orderedMember) bool { return m.key == key })
+}
+
+// set sets the value of the key, the new key is appended.
+func (s *orderedObject) set(key string, value any) {
+	for i, m := range *s {
+		if m.key == key {
+			(*s)[i].value = value
+			return
+		}
+	}
+	*s = append(*s, orderedMember{key: key, value: value})
+}
+
+func (s orderedObject) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, m := range s {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		k, err := json.Marshal(m.key)
+		if err != nil {
+			return nil, err
+		}
+		v, err := json.Marshal(m.value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", m.key, err)
+		}
+		b.Write(k)
+		b.WriteByte(':')
+		b.Write(v)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+func marshalIndent(v any) ([]byte, error) {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
+
+type configSchema struct {
+	config        *config
+	schemaVersion int // 0 if the documents are not versioned
+}
+
+// generate returns the JSON Schema of the config.
+// The properties are the field names and the aliases, the fields of func and chan types are not included.
+func (s *configSchema) generate() ([]byte, error) {
+	var properties orderedObject
+	if s.schemaVersion > 0 {
+		properties.set(schemaVersionKey, orderedObject{
+			{key: "type", value: "integer"},
+			{key: "minimum", value: 1},
+			{key: "maximum", value: s.schemaVersion},
+			{key: "description", value: "Version of the document."},
+		})
+	}
+	for _, f := range s.config.fields {
+		p, ok := s.fieldSchema(f)
+		if !ok {
+			continue
+		}
+		properties.set(f.fieldName, p)
+		for _, a := range f.aliases() {
+			properties.set(a, orderedObject{
+				{key: "$ref", value: "#/properties/" + f.fieldName},
+				{key: "deprecated", value: true},
+			})
+		}
+	}
+	var r orderedObject
+	r.set("$schema", jsonSchemaDraft)
+	r.set("title", s.config.typeName)
+	r.set("type", "object")
+	r.set("properties", properties)
+	r.set("additionalProperties", false)
+	return marshalIndent(r)
+}
+
+func (s *configSchema) fieldSchema(f *configField) (orderedObject, bool) {
+	r, ok := typeSchema(f.typeExpr, s.config.types)
+	if !ok {
+		return nil, false
+	}
+	if d := f.doc(); d != "" {
+		r.set("description", d)
+	}
+	if _, ok := f.deprecated(); ok {
+		r.set("deprecated", true)
+	}
+	if f.isSecret() {
+		r.set("writeOnly", true)
+	} else if v, ok := f.defaultValue(); ok {
+		if x, ok := goExprToJSON(v); ok {
+			r.set("default", x)
+		}
+	}
+	kind := schemaKind(r)
+	if xs, ok := f.enum(); ok {
+		vs := make([]any, len(xs))
+		for i, x := range xs {
+			switch kind {
+			case "integer", "number":
+				if n, ok := jsonNumber(x); ok {
+					vs[i] = n
+				} else {
+					vs[i] = x
+				}
+			case "boolean":
+				vs[i] = x == "true"
+			default:
+				vs[i] = x
+			}
+		}
+		r.set("enum", vs)
+	}
+	for _, c := range []struct {
+		tag      string
+		keywords map[string]string
+	}{
+		{
+			tag: "min",
+			keywords: map[string]string{
+				"integer": "minimum",
+				"number":  "minimum",
+				"string":  "minLength",
+				"array":   "minItems",
+			},
+		},
+		{
+			tag: "max",
+			keywords: map[string]string{
+				"integer": "maximum",
+				"number":  "maximum",
+				"string":  "maxLength",
+				"array":   "maxItems",
+			},
+		},
+	} {
+		v, ok := f.tags[c.tag]
+		if !ok {
+			continue
+		}
+		k, ok := c.keywords[kind]
+		if !ok {
+			continue
+		}
+		if n, ok := jsonNumber(v); ok {
+			r.set(k, n)
+		}
+	}
+	return r, true
+}
+
+// schemaKind returns the type of the schema if it is a single type.
+func schemaKind(v orderedObject) string {
+	for _, m := range v {
+		if m.key == "type" {
+			if x, ok := m.value.(string); ok {
+				return x
+			}
+		}
+	}
+	return ""
+}
+
+var basicTypeSchemas = map[string]string{
+	"bool":       "boolean",
+	"string":     "string",
+	"int":        "integer",
+	"int8":       "integer",
+	"int16":      "integer",
+	"int32":      "integer",
+	"int64":      "integer",
+	"uint":       "integer",
+	"uint8":      "integer",
+	"uint16":     "integer",
+	"uint32":     "integer",
+	"uint64":     "integer",
+	"uintptr":    "integer",
+	"byte":       "integer",
+	"rune":       "integer",
+	"float32":    "number",
+	"float64":    "number",
+	"complex64":  "",
+	"complex128": "",
+}
+
+// typeSchema returns the schema of the Go type.
+// The named types are resolved in pkg into the schemas of their underlying types,
+// they are unconstrained if pkg is nil or they are not found.
+// It reports false if the type cannot be represented in JSON.
+func typeSchema(typ ast.Expr, pkg *types.Package) (orderedObject, bool) {
+	var r orderedObject
+	switch t := typ.(type) {
+	case *ast.ParenExpr:
+		return typeSchema(t.X, pkg)
+	case *ast.Ident:
+		x, ok := basicTypeSchemas[t.Name]
+		if !ok { // named type
+			return namedTypeSchema(t, pkg)
+		}
+		if x == "" {
+			return nil, false
+		}
+		r.set("type", x)
+		if strings.HasPrefix(t.Name, "uint") || t.Name == "byte" {
+			r.set("minimum", 0)
+		}
+		return r, true
+	case *ast.SelectorExpr:
+		if x, ok := t.X.(*ast.Ident); ok && x.Name == "time" && t.Sel.Name == "Duration" {
+			r.set("type", []string{"string", "integer"})
+			return r, true
+		}
+		return namedTypeSchema(t, pkg)
+	case *ast.StarExpr:
+		x, ok := typeSchema(t.X, pkg)
+		if !ok {
+			return nil, false
+		}
+		return nullableSchema(x), true
+	case *ast.ArrayType:
+		if x, ok := t.Elt.(*ast.Ident); ok && x.Name == "byte" && t.Len == nil {
+			r.set("type", "string")
+			r.set("contentEncoding", "base64")
+			return r, true
+		}
+		items, ok := typeSchema(t.Elt, pkg)
+		if !ok {
+			return nil, false
+		}
+		r.set("type", "array")
+		r.set("items", items)
+		if t.Len != nil {
+			if x, ok := t.Len.(*ast.BasicLit); ok && x.Kind == token.INT {
+				r.set("minItems", json.Number(x.Value))
+				r.set("maxItems", json.Number(x.Value))
+			}
+		}
+		return r, true
+	case *ast.MapType:
+		v, ok := typeSchema(t.Value, pkg)
+		if !ok {
+			return nil, false
+		}
+		r.set("type", "object")
+		r.set("additionalProperties", v)
+		return r, true
+	case *ast.FuncType, *ast.ChanType:
+		return nil, false
+	default:
+		return r, true
+	}
+}
+
+// nullableSchema returns the schema of the pointer to the type of the schema x.
+func nullableSchema(x orderedObject) orderedObject {
+	if k := schemaKind(x); k != "" {
+		for i, m := range x {
+			if m.key == "type" {
+				x[i].value = []string{k, "null"}
+			}
+		}
+		return x
+	}
+	if len(x) == 0 {
+		return x
+	}
+	return orderedObject{{key: "anyOf", value: []any{x, orderedObject{{key: "type", value: "null"}}}}}
+}
+
+// namedTypeSchema returns the schema of the named type resolved in pkg, unconstrained if not found.
+func namedTypeSchema(typ ast.Expr, pkg *types.Package) (orderedObject, bool) {
+	if t := lookupType(pkg, typ); t != nil {
+		return goTypeSchema(t, map[*types.Named]bool{})
+	}
+	return orderedObject{}, true
+}
+
+// lookupType returns the type named by typ in pkg, e.g. DBConfig declared in pkg or http.Header imported by pkg.
+// It returns nil if not found.
+func lookupType(pkg *types.Package, typ ast.Expr) types.Type {
+	if pkg == nil {
+		return nil
+	}
+	var (
+		scope = pkg.Scope()
+		name  string
+	)
+	switch t := typ.(type) {
+	case *ast.Ident:
+		name = t.Name
+	case *ast.SelectorExpr:
+		x, ok := t.X.(*ast.Ident)
+		if !ok {
+			return nil
+		}
+		i := slices.IndexFunc(pkg.Imports(), func(p *types.Package) bool { return p.Name() == x.Name })
+		if i < 0 || !token.IsExported(t.Sel.Name) {
+			return nil
+		}
+		scope = pkg.Imports()[i].Scope()
+		name = t.Sel.Name
+	default:
+		return nil
+	}
+	if x, ok := scope.Lookup(name).(*types.TypeName); ok {
+		return x.Type()
+	}
+	return nil
+}
+
+// goTypeSchema returns the schema of the type, the counterpart of typeSchema for the resolved types.
+// The structs are objects whose properties are the fields encoded by encoding/json.
+// The types that have MarshalJSON and the recursive types are unconstrained.
+func goTypeSchema(typ types.Type, seen map[*types.Named]bool) (orderedObject, bool) {
+	var r orderedObject
+	switch t := types.Unalias(typ).(type) {
+	case *types.Named:
+		if o := t.Obj(); o.Pkg() != nil && o.Pkg().Path() == "time" && o.Name() == "Duration" {
+			r.set("type", []string{"string", "integer"})
+			return r, true
+		}
+		if hasMethod(t, "MarshalJSON") || seen[t] {
+			return r, true
+		}
+		if hasMethod(t, "MarshalText") {
+			r.set("type", "string")
+			return r, true
+		}
+		seen[t] = true
+		defer delete(seen, t)
+		return goTypeSchema(t.Underlying(), seen)
+	case *types.Basic:
+		x := basicTypeSchemas[t.Name()]
+		if x == "" {
+			return nil, false
+		}
+		r.set("type", x)
+		if t.Info()&types.IsUnsigned != 0 {
+			r.set("minimum", 0)
+		}
+		return r, true
+	case *types.Pointer:
+		x, ok := goTypeSchema(t.Elem(), seen)
+		if !ok {
+			return nil, false
+		}
+		return nullableSchema(x), true
+	case *types.Slice:
+		if x, ok := t.Elem().(*types.Basic); ok && x.Kind() == types.Byte {
+			r.set("type", "string")
+			r.set("contentEncoding", "base64")
+			return r, true
+		}
+		items, ok := goTypeSchema(t.Elem(), seen)
+		if !ok {
+			return nil, false
+		}
+		r.set("type", "array")
+		r.set("items", items)
+		return r, true
+	case *types.Array:
+		items, ok := goTypeSchema(t.Elem(), seen)
+		if !ok {
+			return nil, false
+		}
+		r.set("type", "array")
+		r.set("items", items)
+		r.set("minItems", t.Len())
+		r.set("maxItems", t.Len())
+		return r, true
+	case *types.Map:
+		v, ok := goTypeSchema(t.Elem(), seen)
+		if !ok {
+			return nil, false
+		}
+		r.set("type", "object")
+		r.set("additionalProperties", v)
+		return r, true
+	case *types.Struct:
+		r.set("type", "object")
+		r.set("properties", structProperties(t, seen))
+		return r, true
+	case *types.Signature, *types.Chan:
+		return nil, false
+	default:
+		return r, true
+	}
+}
+
+// structProperties returns the properties of the fields of the struct encoded by encoding/json,
+// named by the json tags and including the fields of the embedded structs.
+func structProperties(t *types.Struct, seen map[*types.Named]bool) orderedObject {
+	r := orderedObject{}
+	for i := 0; i < t.NumFields(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(reflect.StructTag(t.Tag(i)).Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if f.Embedded() && name == "" {
+			typ := f.Type()
+			if p, ok := typ.Underlying().(*types.Pointer); ok {
+				typ = p.Elem()
+			}
+			if x, ok := typ.Underlying().(*types.Struct); ok {
+				for _, m := range structProperties(x, seen) {
+					if !r.has(m.key) { // the shallower fields win
+						r.set(m.key, m.value)
+					}
+				}
+				continue
+			}
+		}
+		if !f.Exported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name()
+		}
+		if x, ok := goTypeSchema(f.Type(), seen); ok {
+			r.set(name, x)
+		}
+	}
+	return r
+}
+
+// hasMethod reports whether the pointer to the type has the method.
+func hasMethod(t types.Type, name string) bool {
+	return types.NewMethodSet(types.NewPointer(t)).Lookup(nil, name) != nil
+}
+
+// jsonNumber converts the Go number literal into JSON number.
+func jsonNumber(v string) (json.Number, bool) {
+	if x, err := strconv.ParseInt(v, 0, 64); err == nil {
+		return json.Number(strconv.FormatInt(x, 10)), true
+	}
+	if x, err := strconv.ParseFloat(v, 64); err == nil {
+		return json.Number(strconv.FormatFloat(x, 'g', -1, 64)), true
+	}
+	return "", false
+}
+
+// goExprToJSON converts the Go literal into JSON value.
+// It reports false if the expression is not a literal.
+func goExprToJSON(expr string) (any, bool) {
+	x, err := parser.ParseExpr(expr)
+	if err != nil {
+		return nil, false
+	}
+	return goLiteralToJSON(x)
+}
+
+func goLiteralToJSON(expr ast.Expr) (any, bool) {
+	switch x := expr.(type) {
+	case *ast.ParenExpr:
+		return goLiteralToJSON(x.X)
+	case *ast.BasicLit:
+		switch x.Kind {
+		case token.INT, token.FLOAT:
+			return jsonNumber(x.Value)
+		case token.STRING:
+			v, err := strconv.Unquote(x.Value)
+			if err != nil {
+				return nil, false
+			}
+			return v, true
+		default:
+			return nil, false
+		}
+	case *ast.UnaryExpr:
+		if x.Op != token.SUB {
+			return nil, false
+		}
+		v, ok := goLiteralToJSON(x.X)
+		if n, isNumber := v.(json.Number); ok && isNumber {
+			return json.Number("-" + string(n)), true
+		}
+		return nil, false
+	case *ast.Ident:
+		switch x.Name {
+		case "true":
+			return true, true
+		case "false":
+			return false, true
+		case "nil":
+			return nil, true
+		default:
+			return nil, false
+		}
+	case *ast.CompositeLit:
+		switch x.Type.(type) {
+		case *ast.ArrayType:
+			r := []any{}
+			for _, e := range x.Elts {
+				if _, ok := e.(*ast.KeyValueExpr); ok {
+					return nil, false
+				}
+				v, ok := goLiteralToJSON(e)
+				if !ok {
+					return nil, false
+				}
+				r = append(r, v)
+			}
+			return r, true
+		case *ast.MapType:
+			r := orderedObject{}
+			for _, e := range x.Elts {
+				kv, ok := e.(*ast.KeyValueExpr)
+				if !ok {
+					return nil, false
+				}
+				k, ok := goLiteralToJSON(kv.Key)
+				if !ok {
+					return nil, false
+				}
+				v, ok := goLiteralToJSON(kv.Value)
+				if !ok {
+					return nil, false
+				}
+				r.set(fmt.Sprint(k), v)
+			}
+			return r, true
+		default:
+			return nil, false
+		}
+	default:
+		return nil, false
+	}
+}
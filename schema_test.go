@@ -0,0 +1,254 @@
+package main
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSchema(t *testing.T) {
+	g := newGenerator(
+		`Size uint @default=10 @min=1 @max=100 @doc="Size of the buffer."|Name string @enum=a,b @default="a"|Tags []string @default=[]string{"x"} @max=3|Limits map[string]*int|Timeout time.Duration|Handler func()|Password string @secret @default="x"|Reader io.Reader|Len int @alias=Length @deprecated="use Size"`,
+		"Config",
+		"Item",
+		"Builder",
+		"Option",
+		generatorOptions{},
+	)
+	got, err := (&configSchema{config: g.conf}).generate()
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "title": "Config",
+  "type": "object",
+  "properties": {
+    "Size": {
+      "type": "integer",
+      "minimum": 1,
+      "description": "Size of the buffer.",
+      "default": 10,
+      "maximum": 100
+    },
+    "Name": {
+      "type": "string",
+      "default": "a",
+      "enum": [
+        "a",
+        "b"
+      ]
+    },
+    "Tags": {
+      "type": "array",
+      "items": {
+        "type": "string"
+      },
+      "default": [
+        "x"
+      ],
+      "maxItems": 3
+    },
+    "Limits": {
+      "type": "object",
+      "additionalProperties": {
+        "type": [
+          "integer",
+          "null"
+        ]
+      }
+    },
+    "Timeout": {
+      "type": [
+        "string",
+        "integer"
+      ]
+    },
+    "Password": {
+      "type": "string",
+      "writeOnly": true
+    },
+    "Reader": {},
+    "Len": {
+      "type": "integer",
+      "deprecated": true
+    },
+    "Length": {
+      "$ref": "#/properties/Len",
+      "deprecated": true
+    }
+  },
+  "additionalProperties": false
+}
+`, string(got))
+}
+
+func TestSchemaNamedTypes(t *testing.T) {
+	const src = `package p
+
+import "time"
+
+type Base struct {
+	ID int
+}
+
+type DBConfig struct {
+	Base
+	Host    string
+	Port    uint16 ` + "`json:\"port\"`" + `
+	Timeout time.Duration
+	Secret  string ` + "`json:\"-\"`" + `
+	Replica *DBConfig
+	Tags    Tags
+	handler func()
+}
+
+type Tags []string
+
+type Level int
+
+type Handler func()
+`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "p.go", src, 0)
+	if !assert.Nil(t, err) {
+		return
+	}
+	pkg, err := (&types.Config{Importer: importer.Default()}).Check("p", fset, []*ast.File{f}, nil)
+	if !assert.Nil(t, err) {
+		return
+	}
+	g := newGenerator("DB DBConfig|Level Level|At time.Time|Handler Handler|Unknown Unknown", "Config", "Item", "Builder", "Option", generatorOptions{})
+	g.conf.types = pkg
+
+	got, err := (&configSchema{config: g.conf}).generate()
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "title": "Config",
+  "type": "object",
+  "properties": {
+    "DB": {
+      "type": "object",
+      "properties": {
+        "ID": {
+          "type": "integer"
+        },
+        "Host": {
+          "type": "string"
+        },
+        "port": {
+          "type": "integer",
+          "minimum": 0
+        },
+        "Timeout": {
+          "type": [
+            "string",
+            "integer"
+          ]
+        },
+        "Replica": {},
+        "Tags": {
+          "type": "array",
+          "items": {
+            "type": "string"
+          }
+        }
+      }
+    },
+    "Level": {
+      "type": "integer"
+    },
+    "At": {},
+    "Unknown": {}
+  },
+  "additionalProperties": false
+}
+`, string(got))
+
+	got, err = g.example().json()
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, `{
+  "DB": {
+    "ID": 0,
+    "Host": "",
+    "port": 0,
+    "Timeout": 0,
+    "Replica": null,
+    "Tags": []
+  },
+  "Level": 0,
+  "At": null,
+  "Unknown": null
+}
+`, string(got))
+}
+
+func TestGoExprToJSON(t *testing.T) {
+	for _, tc := range []struct {
+		expr string
+		want any
+		ok   bool
+	}{
+		{expr: "10", want: jsonNumberOf("10"), ok: true},
+		{expr: "0x10", want: jsonNumberOf("16"), ok: true},
+		{expr: "-1.5", want: jsonNumberOf("-1.5"), ok: true},
+		{expr: `"s"`, want: "s", ok: true},
+		{expr: "true", want: true, ok: true},
+		{expr: "nil", want: nil, ok: true},
+		{expr: `[]string{"a", "b"}`, want: []any{"a", "b"}, ok: true},
+		{expr: `map[string]int{"a": 1}`, want: orderedObject{{key: "a", value: jsonNumberOf("1")}}, ok: true},
+		{expr: "3*time.Second"},
+		{expr: "x"},
+	} {
+		t.Run(tc.expr, func(t *testing.T) {
+			got, ok := goExprToJSON(tc.expr)
+			assert.Equal(t, tc.ok, ok)
+			if tc.ok {
+				assert.Equal(t, tc.want, got)
+			}
+		})
+	}
+}
+
+func jsonNumberOf(v string) any {
+	n, _ := jsonNumber(v)
+	return n
+}
+
+func TestSchemaVersion(t *testing.T) {
+	g := newGenerator("Size int", "Config", "Item", "Builder", "Option", generatorOptions{
+		schemaVersion: 2,
+	})
+	got, err := (&configSchema{config: g.conf, schemaVersion: 2}).generate()
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "title": "Config",
+  "type": "object",
+  "properties": {
+    "schemaVersion": {
+      "type": "integer",
+      "minimum": 1,
+      "maximum": 2,
+      "description": "Version of the document."
+    },
+    "Size": {
+      "type": "integer"
+    }
+  },
+  "additionalProperties": false
+}
+`, string(got))
+}
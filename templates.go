@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+//go:embed templates/*.tmpl
+var defaultTemplateFS embed.FS
+
+// templateNames are the names of the templates of the sections that can be overridden.
+var templateNames = []string{
+	"item.tmpl",
+	"config.tmpl",
+	"builder.tmpl",
+	"option.tmpl",
+}
+
+var templateFuncs = template.FuncMap{
+	"capitalize":   capitalize,
+	"decapitalize": decapitalize,
+	"join":         strings.Join,
+	"quote":        strconv.Quote,
+}
+
+func defaultTemplates() *template.Template {
+	return template.Must(template.New("").Funcs(templateFuncs).ParseFS(defaultTemplateFS, "templates/*.tmpl"))
+}
+
+// loadTemplates returns the default templates overridden by the templates in dir.
+// The files in dir replace the default templates of the same names,
+// and the templates defined in them by {{define}} replace the default ones of the same names.
+func loadTemplates(dir string) (*template.Template, error) {
+	t := defaultTemplates()
+	if dir == "" {
+		return t, nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("templates: %w", err)
+	}
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".tmpl" {
+			continue
+		}
+		if !slices.Contains(templateNames, e.Name()) {
+			return nil, fmt.Errorf("templates: unknown template %s, want one of %s", e.Name(), strings.Join(templateNames, ", "))
+		}
+		b, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("templates: %w", err)
+		}
+		if _, err := t.New(e.Name()).Parse(string(b)); err != nil {
+			return nil, fmt.Errorf("templates: %w", err)
+		}
+	}
+	return t, nil
+}
+
+// templateData is the data given to the templates.
+type templateData struct {
+	// Config is the type name of the config, e.g. Config.
+	Config string
+	// ConfigItem is the type name of the item, e.g. ConfigItem.
+	ConfigItem string
+	// ConfigItemConstructor is the constructor of the item, e.g. NewConfigItem.
+	ConfigItemConstructor string
+	// ConfigBuilder is the type name of the builder, e.g. ConfigBuilder.
+	ConfigBuilder string
+	// ConfigBuilderConstructor is the constructor of the builder, e.g. NewConfigBuilder.
+	ConfigBuilderConstructor string
+	// ConfigOption is the type name of the option, e.g. ConfigOption.
+	ConfigOption string
+	// UnfreezeToken is the type name of the token of Unfreeze, empty without -freeze.
+	UnfreezeToken string
+	// ErrFrozen is the error of setting the frozen item, empty without -freeze.
+	ErrFrozen string
+	// Redacted is shown instead of the values of the secret fields.
+	Redacted string
+	// Options are the options of the command line, e.g. .Options.Freeze for -freeze.
+	Options modelOptions
+	// Item reports the unexported methods of the item that the other sections call.
+	Item   templateItem
+	Fields []templateField
+}
+
+// templateItem reports the unexported methods and fields of the item required by the options.
+type templateItem struct {
+	// Clone requires clone.
+	Clone bool
+	// Equal requires equal.
+	Equal bool
+	// DeepCopy requires copyConfigItemValue, the copy function of the values of the named and struct types.
+	DeepCopy bool
+	// Reset requires Reset.
+	Reset bool
+	// Secret requires the secret field and markSecret.
+	Secret bool
+	// Loader requires setText and loadText.
+	Loader bool
+	// JSONLoader requires loadJSON.
+	JSONLoader bool
+}
+
+type templateField struct {
+	// Name is the name of the field, e.g. Size.
+	Name string
+	// Type is the type of the field, e.g. int.
+	Type string
+	// ItemName is the name of the item in the config, unexported with -accessor.
+	ItemName string
+	// BuilderName is the name of the field of the builder.
+	BuilderName string
+	// Default is the Go expression of @default.
+	Default    string
+	HasDefault bool
+	Secret     bool
+	// Doc is @doc.
+	Doc string
+	// Deprecated is the message of @deprecated.
+	Deprecated string
+	// Aliases are the old names of the field by @alias.
+	Aliases []string
+	// Comment is the lines of the doc comment, e.g. "// Size of the buffer.", including the deprecation.
+	Comment []string
+	// Tags are the tags without @, the values of the flag tags are empty.
+	Tags map[string]string
+}
+
+// HasDefaults reports whether any field has @default.
+func (s *templateData) HasDefaults() bool {
+	for _, f := range s.Fields {
+		if f.HasDefault {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *generator) templateData() *templateData {
+	d := &templateData{
+		Config:                   s.conf.typeName,
+		ConfigItem:               s.item.typeName,
+		ConfigItemConstructor:    s.item.constructor,
+		ConfigBuilder:            s.builder.typeName,
+		ConfigBuilderConstructor: s.builder.constructor,
+		ConfigOption:             s.option.typeName,
+		Redacted:                 redacted,
+		Options:                  newModelOptions(s.opts),
+		Item: templateItem{
+			Clone:      s.item.needClone,
+			Equal:      s.item.needEqual,
+			DeepCopy:   s.item.needDeepCopy,
+			Reset:      s.item.needReset,
+			Secret:     s.item.needSecret,
+			Loader:     s.item.needLoader,
+			JSONLoader: s.item.needJSONLoader,
+		},
+		Fields: make([]templateField, len(s.conf.fields)),
+	}
+	if s.freeze != nil {
+		d.UnfreezeToken = s.freeze.tokenType
+		d.ErrFrozen = s.freeze.errName
+	}
+	for i, f := range s.conf.fields {
+		var b stringBuilder
+		f.writeDoc(&b)
+		var comment []string
+		if c := strings.TrimSuffix(b.String(), "\n"); c != "" {
+			comment = strings.Split(c, "\n")
+		}
+		v, ok := f.defaultValue()
+		deprecated, _ := f.deprecated()
+		d.Fields[i] = templateField{
+			Name:        f.fieldName,
+			Type:        f.typeName,
+			ItemName:    s.conf.itemName(f),
+			BuilderName: s.builder.fieldName(i),
+			Default:     v,
+			HasDefault:  ok,
+			Secret:      f.isSecret(),
+			Doc:         f.doc(),
+			Deprecated:  deprecated,
+			Aliases:     f.aliases(),
+			Comment:     comment,
+			Tags:        f.tags,
+		}
+	}
+	return d
+}
+
+// execute returns the section generated by the template.
+func (s *generator) execute(name string) (string, error) {
+	var b bytes.Buffer
+	if err := s.templates.ExecuteTemplate(&b, name, s.templateData()); err != nil {
+		return "", fmt.Errorf("failed to execute template: %w", err)
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"go/format"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadTemplates(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		fields    string
+		options   generatorOptions
+		templates map[string]string
+		// want is the generated code by the name of the template.
+		want map[string]string
+		err  bool
+	}{
+		{
+			name:    "override option",
+			fields:  "Size int @default=10",
+			options: generatorOptions{option: true},
+			templates: map[string]string{
+				"option.tmpl": `type {{.ConfigOption}} func(*{{.Config}})
+{{- range .Fields}}
+// Set{{.Name}} sets {{.Name}}.
+func Set{{.Name}}(v {{.Type}}) {{$.ConfigOption}} {
+	return func(c *{{$.Config}}) { c.{{.ItemName}}.Set(v) }
+}
+{{- end}}
+`,
+			},
+			want: map[string]string{
+				"builder.tmpl": `type Builder struct {
+	size int
+}
+
+func (s *Builder) Size(v int) *Builder {
+	s.size = v
+	return s
+}
+func (s *Builder) Build() *Config {
+	return &Config{
+		Size: NewItem(s.size),
+	}
+}
+
+func NewBuilder() *Builder {
+	return &Builder{
+		size: 10,
+	}
+}
+`,
+				"option.tmpl": `type Option func(*Config)
+
+// SetSize sets Size.
+func SetSize(v int) Option {
+	return func(c *Config) { c.Size.Set(v) }
+}
+`,
+			},
+		},
+		{
+			name:   "override define",
+			fields: `Size int @doc="Size of the buffer."`,
+			templates: map[string]string{
+				"item.tmpl": `type {{.ConfigItem}}[T any] struct {
+	value T
+}
+{{- template "item.methods" .}}
+{{- define "item.methods"}}
+func (s *{{.ConfigItem}}[T]) Get() T { return s.value }
+{{- end}}
+`,
+				"builder.tmpl": `{{range .Fields}}
+// {{.Name}} has {{.Tags.doc | quote}}.
+{{- end}}
+`,
+			},
+			want: map[string]string{
+				"item.tmpl": `type Item[T any] struct {
+	value T
+}
+
+func (s *Item[T]) Get() T { return s.value }
+`,
+				"config.tmpl": `type Config struct {
+	// Size of the buffer.
+	Size *Item[int]
+}
+`,
+				"builder.tmpl": `
+// Size has "Size of the buffer.".
+`,
+			},
+		},
+		{
+			name: "unknown template",
+			templates: map[string]string{
+				"items.tmpl": `{{.ConfigItem}}`,
+			},
+			err: true,
+		},
+		{
+			name: "invalid template",
+			templates: map[string]string{
+				"item.tmpl": `{{.ConfigItem`,
+			},
+			err: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tc.templates {
+				assert.Nil(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0600))
+			}
+			templates, err := loadTemplates(dir)
+			if tc.err {
+				assert.NotNil(t, err)
+				return
+			}
+			if !assert.Nil(t, err) {
+				return
+			}
+			g := newGenerator(tc.fields, "Config", "Item", "Builder", "Option", tc.options)
+			g.templates = templates
+			for name, want := range tc.want {
+				code, err := g.execute(name)
+				assert.Nil(t, err, name)
+				got, err := format.Source([]byte(code))
+				assert.Nil(t, err, name)
+				w, err := format.Source([]byte(want))
+				assert.Nil(t, err, name)
+				assert.Equal(t, string(w), string(got), name)
+			}
+		})
+	}
+}
+
+func TestGenerateTemplateError(t *testing.T) {
+	dir := t.TempDir()
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "item.tmpl"), []byte(`{{.Unknown}}`), 0600))
+	templates, err := loadTemplates(dir)
+	if !assert.Nil(t, err) {
+		return
+	}
+	g := newGenerator("Size int", "Config", "Item", "Builder", "Option", generatorOptions{})
+	g.templates = templates
+	assert.NotNil(t, g.generate())
+}
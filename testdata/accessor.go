@@ -0,0 +1,32 @@
+package main
+
+type sizer interface {
+	Size() int
+}
+
+func check(ok bool, msg string) {
+	if !ok {
+		panic(msg)
+	}
+}
+
+func main() {
+	var s sizer = NewBuilder().Size(10).Build()
+	check(s.Size() == 10, "accessor")
+
+	c := NewBuilder().Size(10).Build()
+	c.Apply(WithSize(20), WithAddr("localhost"))
+	check(c.Size() == 20, "option")
+	check(c.Addr() == "localhost", "option addr")
+
+	var zero Config
+	check(zero.Size() == 0, "nil item")
+	var nilConfig *Config
+	check(nilConfig.Addr() == "", "nil config")
+
+	d := c.Clone()
+	d.Apply(WithSize(30))
+	diff := c.Diff(d)
+	check(len(diff) == 1 && diff[0].Field == "Size", "diff")
+	check(c.Equal(c.Clone()), "equal")
+}
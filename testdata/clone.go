@@ -0,0 +1,79 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"reflect"
+)
+
+// Labels is the named type whose underlying type is unknown to goconfig.
+type Labels map[string][]string
+
+func check(ok bool, msg string) {
+	if !ok {
+		panic(msg)
+	}
+}
+
+func main() {
+	c := NewBuilder().
+		Size(10).
+		Tags([]string{"a", "b"}).
+		Groups(map[string][]int{"x": {1, 2}}).
+		Reader(os.Stdin).
+		Handler(func() {}).
+		Header(http.Header{"A": {"a"}}).
+		Labels(Labels{"x": {"1"}}).
+		Sets([2]Labels{{"y": {"2"}}}).
+		Build()
+	c.Apply(WithTags([]string{"c"}))
+
+	d := c.Clone()
+	check(d != c, "new config")
+	check(d.Size != c.Size, "new item")
+	check(c.Equal(d), "equal")
+	check(d.Equal(c), "equal reverse")
+	check(len(c.Diff(d)) == 0, "no diff")
+	check(d.Tags.IsModified(), "modified is cloned")
+	check(reflect.DeepEqual(d.Tags.Default(), []string{"a", "b"}), "default is cloned")
+	check(d.Reader.Get() == os.Stdin, "reader is cloned")
+	check(d.Handler.Get() != nil, "func is cloned")
+
+	d.Tags.Get()[0] = "changed"
+	d.Tags.Default()[0] = "changed"
+	d.Groups.Get()["x"][0] = 100
+	d.Groups.Get()["y"] = nil
+	check(c.Tags.Get()[0] == "c", "value is deep copied")
+	check(c.Tags.Default()[0] == "a", "default is deep copied")
+	check(c.Groups.Get()["x"][0] == 1, "map value is deep copied")
+	check(len(c.Groups.Get()) == 1, "map is deep copied")
+
+	n := c.Clone()
+	n.Header.Get()["A"][0] = "changed"
+	n.Labels.Get()["x"][0] = "changed"
+	n.Sets.Get()[0]["y"][0] = "changed"
+	check(c.Header.Get()["A"][0] == "a", "named map is deep copied")
+	check(c.Labels.Get()["x"][0] == "1", "local named map is deep copied")
+	check(c.Sets.Get()[0]["y"][0] == "2", "array of named maps is deep copied")
+
+	d.Apply(WithSize(20))
+	check(c.Size.Get() == 10, "item is not shared")
+	check(!c.Equal(d), "not equal")
+	diff := c.Diff(d)
+	check(len(diff) == 3, "diff")
+	check(reflect.DeepEqual(diff[0], ConfigFieldDiff{
+		Field:       "Size",
+		Old:         10,
+		New:         20,
+		OldModified: false,
+		NewModified: true,
+	}), "size diff")
+	check(diff[1].Field == "Tags", "tags diff")
+	check(diff[2].Field == "Groups", "groups diff")
+
+	c.Freeze()
+	e := c.Clone()
+	check(!e.IsFrozen(), "clone is not frozen")
+	e.Apply(WithSize(30))
+	check(e.Size.Get() == 30, "clone is writable")
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"reflect"
+)
+
+func check(ok bool, msg string) {
+	if !ok {
+		panic(msg)
+	}
+}
+
+func main() {
+	if _, ok := ConfigFromContext(context.Background()); ok {
+		panic("empty context")
+	}
+	ctx := WithConfigOverrides(context.Background(), WithLevel("debug"))
+	if _, ok := ConfigFromContext(ctx); ok {
+		panic("overrides without config")
+	}
+
+	c := NewBuilder().Level("info").Tags([]string{"a"}).Build()
+	ctx = NewConfigContext(context.Background(), c)
+	got, ok := ConfigFromContext(ctx)
+	check(ok && got == c, "from context")
+
+	overridden := WithConfigOverrides(ctx, WithLevel("debug"))
+	got, ok = ConfigFromContext(overridden)
+	check(ok && got != c, "overridden config is a clone")
+	check(got.Level.Get() == "debug", "overridden level")
+	check(c.Level.Get() == "info", "original level")
+
+	got.Tags.Get()[0] = "b"
+	check(reflect.DeepEqual(c.Tags.Get(), []string{"a"}), "tags are copied")
+}
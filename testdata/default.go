@@ -0,0 +1,24 @@
+package main
+
+import (
+	"reflect"
+	"time"
+)
+
+func check(ok bool, msg string) {
+	if !ok {
+		panic(msg)
+	}
+}
+
+func main() {
+	c := NewBuilder().Build()
+	check(c.Size.Get() == 10, "default size")
+	check(c.Addr.Get() == "localhost:8080", "default addr")
+	check(c.Timeout.Get() == 3*time.Second, "default timeout")
+	check(reflect.DeepEqual(c.Tags.Get(), []string{"a", "b"}), "default tags")
+
+	c = NewBuilder().Size(20).Build()
+	check(c.Size.Default() == 20, "overwritten default size")
+	check(c.Addr.Get() == "localhost:8080", "default addr is kept")
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+)
+
+func check(ok bool, msg string) {
+	if !ok {
+		panic(msg)
+	}
+}
+
+func main() {
+	var warnings []string
+	ConfigDeprecationLogger = func(msg string) {
+		warnings = append(warnings, msg)
+	}
+
+	c := NewBuilder().OldSize(10).Build()
+	check(c.Size.Get() == 10, "builder alias")
+	c.Apply(WithOldSize(20))
+	check(c.Size.Get() == 20, "option alias")
+
+	os.Setenv("APP_OLD_SIZE", "30")
+	os.Setenv("APP_LENGTH", "40")
+	os.Setenv("APP_PASS", "secret")
+	for i := 0; i < 2; i++ {
+		c = NewBuilder().Build()
+		check(c.LoadEnv() == nil, "load env")
+		check(c.Size.Get() == 30, "env alias")
+		check(c.Length.Get() == 40, "deprecated env")
+		check(c.Password.Get() == "secret", "secret env alias")
+	}
+	check(reflect.DeepEqual(warnings, []string{
+		"APP_OLD_SIZE is deprecated, use APP_SIZE instead",
+		"APP_LENGTH is deprecated: use Size",
+		"APP_PASS is deprecated, use APP_PASSWORD instead",
+	}), "env warnings")
+
+	os.Setenv("APP_SIZE", "50")
+	c = NewBuilder().Build()
+	check(c.LoadEnv() == nil, "load env")
+	check(c.Size.Get() == 50, "env wins over alias")
+
+	warnings = nil
+	dir, err := os.MkdirTemp("", "deprecation")
+	check(err == nil, "mkdir")
+	defer os.RemoveAll(dir)
+	check(os.WriteFile(filepath.Join(dir, "old_size"), []byte("60\n"), 0o600) == nil, "write file")
+	c = NewBuilder().Build()
+	check(c.LoadDir(dir, true) == nil, "load dir")
+	check(c.Size.Get() == 60, "dir alias")
+	check(reflect.DeepEqual(warnings, []string{
+		"file old_size is deprecated, use Size instead",
+	}), "dir warnings")
+
+	check(os.WriteFile(filepath.Join(dir, "size"), []byte("70\n"), 0o600) == nil, "write file")
+	c = NewBuilder().Build()
+	check(c.LoadDir(dir, true) == nil, "load dir")
+	check(c.Size.Get() == 70, "dir field wins over alias")
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+func check(ok bool, msg string) {
+	if !ok {
+		panic(msg)
+	}
+}
+
+func writeFiles(dir string, files map[string]string) {
+	check(os.MkdirAll(dir, 0755) == nil, "mkdir")
+	for k, v := range files {
+		check(os.WriteFile(filepath.Join(dir, k), []byte(v), 0600) == nil, "write "+k)
+	}
+}
+
+func main() {
+	root, err := os.MkdirTemp("", "goconfig")
+	check(err == nil, "temp dir")
+	defer os.RemoveAll(root)
+
+	plain := filepath.Join(root, "plain")
+	writeFiles(plain, map[string]string{
+		"size":        "20\n",
+		"Tags":        `["a"]`,
+		"DB-PASSWORD": "secret\n",
+		".hidden":     "x",
+	})
+	check(os.Mkdir(filepath.Join(plain, "sub"), 0755) == nil, "mkdir sub")
+	c := NewBuilder().Size(10).Build()
+	check(c.LoadDir(plain, true) == nil, "load plain")
+	check(c.Size.Get() == 20, "size")
+	check(reflect.DeepEqual(c.Tags.Get(), []string{"a"}), "tags")
+	check(c.DBPassword.Get() == "secret", "password")
+
+	writeFiles(plain, map[string]string{
+		"unknown": "x",
+	})
+	c = NewBuilder().Build()
+	check(c.LoadDir(plain, false) == nil, "ignore unknown")
+	err = NewBuilder().Build().LoadDir(plain, true)
+	check(err != nil && strings.Contains(err.Error(), "unknown"), "strict unknown")
+
+	writeFiles(plain, map[string]string{
+		"size": "x",
+	})
+	check(NewBuilder().Build().LoadDir(plain, false) != nil, "invalid size")
+
+	// mounted ConfigMap layout
+	mounted := filepath.Join(root, "mounted")
+	writeFiles(filepath.Join(mounted, "..2024_01_01"), map[string]string{
+		"size": "1",
+	})
+	writeFiles(filepath.Join(mounted, "..2024_01_02"), map[string]string{
+		"size": "2",
+	})
+	check(os.Symlink("..2024_01_01", filepath.Join(mounted, "..data")) == nil, "link data")
+	check(os.Symlink("..data/size", filepath.Join(mounted, "size")) == nil, "link size")
+	c = NewBuilder().Build()
+	check(c.LoadDir(mounted, true) == nil, "load mounted")
+	check(c.Size.Get() == 1, "mounted size")
+
+	// swap ..data
+	check(os.Symlink("..2024_01_02", filepath.Join(mounted, "..data_tmp")) == nil, "link data tmp")
+	check(os.Rename(filepath.Join(mounted, "..data_tmp"), filepath.Join(mounted, "..data")) == nil, "swap data")
+	check(c.LoadDir(mounted, true) == nil, "reload mounted")
+	check(c.Size.Get() == 2, "swapped size")
+}
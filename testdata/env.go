@@ -0,0 +1,81 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+)
+
+type Rule string
+
+func check(ok bool, msg string) {
+	if !ok {
+		panic(msg)
+	}
+}
+
+func clearEnv() {
+	for _, x := range []string{
+		"APP_SIZE",
+		"APP_TIMEOUT",
+		"APP_RULE",
+		"APP_TAGS",
+		"APP_DB_PASSWORD",
+		"APP_DB_PASSWORD_FILE",
+	} {
+		os.Unsetenv(x)
+	}
+}
+
+func main() {
+	dir, err := os.MkdirTemp("", "goconfig")
+	check(err == nil, "temp dir")
+	defer os.RemoveAll(dir)
+
+	newConfig := func() *Config {
+		return NewBuilder().Size(10).Rule("default").Build()
+	}
+
+	clearEnv()
+	c := newConfig()
+	check(c.LoadEnv() == nil, "load nothing")
+	check(!c.Size.IsModified(), "size is not loaded")
+
+	os.Setenv("APP_SIZE", "20")
+	os.Setenv("APP_TIMEOUT", "3s")
+	os.Setenv("APP_RULE", "strict")
+	os.Setenv("APP_TAGS", `["a","b"]`)
+	os.Setenv("APP_DB_PASSWORD", "secret")
+	c = newConfig()
+	check(c.LoadEnv() == nil, "load")
+	check(c.Size.Get() == 20, "size")
+	check(c.Timeout.Get() == 3*time.Second, "timeout")
+	check(c.Rule.Get() == "strict", "rule")
+	check(reflect.DeepEqual(c.Tags.Get(), []string{"a", "b"}), "tags")
+	check(c.DBPassword.Get() == "secret", "password")
+
+	os.Setenv("APP_SIZE", "x")
+	err = newConfig().LoadEnv()
+	check(err != nil && strings.Contains(err.Error(), "APP_SIZE"), "invalid size")
+	os.Setenv("APP_SIZE", "20")
+
+	secretFile := filepath.Join(dir, "db")
+	check(os.WriteFile(secretFile, []byte("from-file\n"), 0600) == nil, "write secret")
+	os.Setenv("APP_DB_PASSWORD_FILE", secretFile)
+	check(newConfig().LoadEnv() != nil, "both password and file")
+
+	os.Unsetenv("APP_DB_PASSWORD")
+	c = newConfig()
+	check(c.LoadEnv() == nil, "load file")
+	check(c.DBPassword.Get() == "from-file", "password from file")
+
+	check(os.Chmod(secretFile, 0644) == nil, "chmod")
+	err = newConfig().LoadEnv()
+	check(err != nil && strings.Contains(err.Error(), "world-readable"), "world-readable file")
+
+	os.Setenv("APP_DB_PASSWORD_FILE", filepath.Join(dir, "missing"))
+	check(newConfig().LoadEnv() != nil, "missing file")
+	clearEnv()
+}
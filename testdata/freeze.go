@@ -0,0 +1,53 @@
+package main
+
+import (
+	"errors"
+)
+
+func check(ok bool, msg string) {
+	if !ok {
+		panic(msg)
+	}
+}
+
+func mustPanic(f func(), msg string) {
+	defer func() {
+		check(recover() != nil, msg)
+	}()
+	f()
+}
+
+func main() {
+	c := NewBuilder().
+		Size(10).
+		Name("init").
+		Build()
+
+	check(!c.IsFrozen(), "not frozen")
+	c.Apply(WithSize(20))
+	check(c.Size.Get() == 20, "get size")
+
+	token := c.Freeze()
+	check(token != nil, "token")
+	check(c.IsFrozen(), "frozen")
+	check(c.Freeze() == nil, "already frozen")
+
+	mustPanic(func() { c.Apply(WithSize(30)) }, "apply panics")
+	mustPanic(func() { c.Name.Set("x") }, "set panics")
+	check(errors.Is(c.TryApply(WithSize(30)), ErrConfigFrozen), "try apply")
+	check(errors.Is(c.Name.TrySet("x"), ErrConfigFrozen), "try set")
+	check(c.Size.Get() == 20, "size is not changed")
+	check(c.Name.Get() == "init", "name is not changed")
+
+	check(token.Unfreeze(), "unfreeze")
+	check(!token.Unfreeze(), "stale token")
+	check(!c.IsFrozen(), "unfrozen")
+	check(c.TryApply(WithSize(30), WithName("reloaded")) == nil, "try apply after unfreeze")
+	check(c.Size.Get() == 30, "get reloaded size")
+	check(c.Name.Get() == "reloaded", "get reloaded name")
+
+	next := c.Freeze()
+	check(!token.Unfreeze(), "old token after refreeze")
+	check(c.IsFrozen(), "refrozen")
+	check(next.Unfreeze(), "unfreeze by new token")
+}
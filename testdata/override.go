@@ -0,0 +1,37 @@
+package main
+
+func check(ok bool, msg string) {
+	if !ok {
+		panic(msg)
+	}
+}
+
+type fakeT struct {
+	cleanups []func()
+}
+
+func (*fakeT) Helper() {}
+func (s *fakeT) Cleanup(f func()) {
+	s.cleanups = append(s.cleanups, f)
+}
+func (s *fakeT) finish() {
+	for i := len(s.cleanups) - 1; i >= 0; i-- {
+		s.cleanups[i]()
+	}
+}
+
+func main() {
+	c := NewBuilder().Size(10).Addr("localhost").Build()
+	c.Apply(WithAddr("example.com"))
+
+	t := &fakeT{}
+	c.Override(t, WithSize(20), WithAddr("test"))
+	check(c.Size.Get() == 20, "overridden size")
+	check(c.Addr.Get() == "test", "overridden addr")
+	t.finish()
+
+	check(c.Size.Get() == 10, "restored size")
+	check(!c.Size.IsModified(), "restored size is not modified")
+	check(c.Addr.Get() == "example.com", "restored addr")
+	check(c.Addr.IsModified(), "restored addr is modified")
+}
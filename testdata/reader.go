@@ -0,0 +1,22 @@
+package main
+
+import "fmt"
+
+func check(ok bool, msg string) {
+	if !ok {
+		panic(msg)
+	}
+}
+
+func addr(c ConfigReader) string {
+	return fmt.Sprintf("%s:%d", c.Addr(), c.Size())
+}
+
+func main() {
+	c := NewBuilder().Size(80).Addr("localhost").Build()
+	check(addr(c) == "localhost:80", "config")
+	check(addr(&FakeConfig{
+		SizeValue: 8080,
+		AddrValue: "example.com",
+	}) == "example.com:8080", "fake")
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+)
+
+func check(ok bool, msg string) {
+	if !ok {
+		panic(msg)
+	}
+}
+
+func main() {
+	c := NewBuilder().
+		Size(10).
+		Name("init").
+		Build()
+
+	check(len(c.ModifiedFields()) == 0, "no modified fields")
+	c.Apply(
+		WithSize(20),
+		WithName("next"),
+		WithTags([]string{"a"}),
+	)
+	check(reflect.DeepEqual(c.ModifiedFields(), []string{"Size", "Name", "Tags"}), "modified fields")
+
+	c.Size.Reset()
+	check(!c.Size.IsModified(), "size is reset")
+	check(c.Size.Get() == 10, "get default size")
+
+	check(c.Reset("Name", "Unknown") != nil, "unknown field")
+	check(c.Name.Get() == "next", "name is not reset by failed reset")
+	check(c.Reset("Name") == nil, "reset name")
+	check(c.Name.Get() == "init", "get default name")
+	check(reflect.DeepEqual(c.ModifiedFields(), []string{"Tags"}), "modified tags")
+
+	c.Apply(WithSize(30))
+	c.ResetAll()
+	check(len(c.ModifiedFields()) == 0, "reset all")
+	check(c.Size.Get() == 10, "get default size after reset all")
+	check(c.Tags.Get() == nil, "get default tags after reset all")
+
+	c.Apply(WithSize(40))
+	token := c.Freeze()
+	check(errors.Is(c.Reset("Size"), ErrConfigFrozen), "reset frozen")
+	check(c.Size.Get() == 40, "frozen size is not reset")
+	token.Unfreeze()
+	check(c.Reset("Size") == nil, "reset unfrozen")
+	check(c.Size.Get() == 10, "unfrozen size is reset")
+}
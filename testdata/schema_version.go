@@ -0,0 +1,68 @@
+package main
+
+import (
+	"strings"
+	"time"
+)
+
+func check(ok bool, msg string) {
+	if !ok {
+		panic(msg)
+	}
+}
+
+func checkErr(err error, contains, msg string) {
+	if err == nil || !strings.Contains(err.Error(), contains) {
+		panic(msg)
+	}
+}
+
+func main() {
+	ConfigDeprecationLogger = func(string) {}
+	RegisterConfigMigration(1, func(doc map[string]any) error {
+		// Size was in KiB
+		if v, ok := doc["SizeKiB"]; ok {
+			doc["Size"] = v
+			delete(doc, "SizeKiB")
+		}
+		return nil
+	})
+
+	c := NewBuilder().Build()
+	checkErr(c.LoadJSON([]byte(`{"SizeKiB": 1}`)), "no migration from schemaVersion 2", "missing migration")
+
+	RegisterConfigMigration(2, func(doc map[string]any) error {
+		if v, ok := doc["Timeout"].(string); ok && !strings.HasSuffix(v, "s") {
+			doc["Timeout"] = v + "s"
+		}
+		if _, ok := doc["Addr"]; !ok {
+			doc["Addr"] = "localhost"
+		}
+		return nil
+	})
+
+	c = NewBuilder().Build()
+	check(c.LoadJSON([]byte(`{"SizeKiB": 9007199254740993, "Timeout": "3"}`)) == nil, "version 1")
+	check(c.Size.Get() == 9007199254740993, "migrated size")
+	check(c.Timeout.Get() == 3*time.Second, "migrated timeout")
+
+	c = NewBuilder().Build()
+	check(c.LoadJSON([]byte(`{"schemaVersion": 3, "Size": 10, "Timeout": 1000, "Host": "localhost"}`)) == nil, "version 3")
+	check(c.Size.Get() == 10, "size")
+	check(c.Timeout.Get() == time.Microsecond, "timeout in nanoseconds")
+	check(c.Addr.Get() == "localhost", "alias")
+
+	checkErr(c.LoadJSON([]byte(`{"schemaVersion": 3, "Addr": "example.com", "Size": "x"}`)), "Size", "invalid size")
+	check(c.Addr.Get() == "localhost", "not applied partially")
+
+	check(c.LoadJSON([]byte(`{"schemaVersion": 3, "Addr": "example.com", "Host": "localhost"}`)) == nil, "field and alias")
+	check(c.Addr.Get() == "example.com", "field wins over alias")
+
+	c = NewBuilder().Build()
+	check(c.LoadJSON([]byte(`null`)) == nil, "null")
+	check(c.Addr.Get() == "localhost", "migrated null")
+
+	checkErr(c.LoadJSON([]byte(`{"schemaVersion": 4}`)), "newer", "newer version")
+	checkErr(c.LoadJSON([]byte(`{"schemaVersion": 3, "Unknown": 1}`)), "unknown key", "unknown key")
+	checkErr(c.LoadJSON([]byte(`{"schemaVersion": 3, "Size": "x"}`)), "Size", "invalid value")
+}
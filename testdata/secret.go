@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+func check(ok bool, msg string) {
+	if !ok {
+		panic(msg)
+	}
+}
+
+const password = "p@ssw0rd"
+
+func checkRedacted(got, msg string) {
+	check(!strings.Contains(got, password), fmt.Sprintf("%s: %s", msg, got))
+	check(strings.Contains(got, "[REDACTED]"), fmt.Sprintf("%s: %s", msg, got))
+}
+
+func main() {
+	c := NewBuilder().
+		Size(10).
+		Password("default").
+		Build()
+	c.Apply(WithPassword(password))
+
+	check(c.Password.Get() == password, "get password")
+	check(c.Password.IsSecret(), "password is secret")
+	check(!c.Size.IsSecret(), "size is not secret")
+	check(fmt.Sprint(c.Size) == "10", "size is shown")
+
+	checkRedacted(fmt.Sprint(c.Password), "String")
+	checkRedacted(fmt.Sprintf("%#v", c.Password), "GoString")
+	checkRedacted(fmt.Sprintf("%v", c.Values()), "values String")
+	checkRedacted(fmt.Sprintf("%+v", c.Values()), "values String with field names")
+	checkRedacted(fmt.Sprintf("%#v", c.Values()), "values GoString")
+
+	b, err := json.Marshal(c)
+	check(err == nil, "marshal")
+	checkRedacted(string(b), "MarshalJSON")
+	check(strings.Contains(string(b), `"Size":10`), "size is marshaled")
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+	logger.Info("config", "password", c.Password, "values", c.Values())
+	checkRedacted(buf.String(), "LogValue")
+
+	d := c.Clone()
+	d.Apply(WithPassword(password + "2"))
+	for _, x := range c.Diff(d) {
+		checkRedacted(fmt.Sprintf("%v", x), "Diff")
+	}
+	check(d.Password.IsSecret(), "clone is secret")
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+)
+
+func check(ok bool, msg string) {
+	if !ok {
+		panic(msg)
+	}
+}
+
+var errInvalidSize = errors.New("invalid size")
+
+func (s *Config) Validate() error {
+	if s.Size.Get() < 0 {
+		return errInvalidSize
+	}
+	return nil
+}
+
+func main() {
+	c := NewBuilder().
+		Size(10).
+		Name("init").
+		Handler(func() {}).
+		Build()
+
+	changed, err := c.ApplyTx(WithName("next"), WithSize(-1))
+	check(errors.Is(err, errInvalidSize), "invalid")
+	check(changed == nil, "nothing changed")
+	check(!c.Name.IsModified(), "name is not modified")
+	check(c.Name.Get() == "init", "name is rolled back")
+	check(c.Size.Get() == 10, "size is rolled back")
+
+	changed, err = c.ApplyTx(WithName("next"), WithSize(20))
+	check(err == nil, "valid")
+	check(reflect.DeepEqual(changed, []string{"Size", "Name"}), "changed fields")
+	check(c.Name.Get() == "next", "get name")
+	check(c.Size.Get() == 20, "get size")
+	check(!c.Tags.IsModified(), "tags is not modified")
+
+	changed, err = c.ApplyTx(WithSize(20), WithTags([]string{"a"}))
+	check(err == nil, "valid tags")
+	check(reflect.DeepEqual(changed, []string{"Tags"}), "changed tags")
+	check(reflect.DeepEqual(c.Tags.Get(), []string{"a"}), "get tags")
+
+	changed, err = c.ApplyTx(WithSize(1))
+	check(err == nil, "valid size")
+	check(reflect.DeepEqual(changed, []string{"Size"}), "func is unchanged")
+
+	changed, err = c.ApplyTx(WithHandler(nil))
+	check(err == nil, "valid handler")
+	check(reflect.DeepEqual(changed, []string{"Handler"}), "func is changed to nil")
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"net/http"
+	"reflect"
+)
+
+func check(ok bool, msg string) {
+	if !ok {
+		panic(msg)
+	}
+}
+
+func main() {
+	c := NewBuilder().
+		Size(10).
+		Name("init").
+		Build()
+	c.Apply(
+		WithName("next"),
+		WithTags([]string{"a"}),
+		WithHeader(http.Header{"A": {"a"}}),
+	)
+
+	v := c.Values()
+	check(reflect.DeepEqual(v, ConfigValues{
+		Size:   10,
+		Name:   "next",
+		Tags:   []string{"a"},
+		Header: http.Header{"A": {"a"}},
+	}), "values")
+	v.Tags[0] = "changed"
+	check(c.Tags.Get()[0] == "a", "values are copied")
+	v.Header["A"][0] = "changed"
+	check(c.Header.Get()["A"][0] == "a", "named map values are copied")
+
+	d := c.ToBuilder().Size(20).Build()
+	check(d.Size.Get() == 20, "derived size")
+	check(d.Name.Get() == "init", "derived name is default")
+	check(!d.Name.IsModified(), "derived name is not modified")
+
+	v.Apply(d)
+	check(d.Size.Get() == 10, "applied size")
+	check(d.Name.Get() == "next", "applied name")
+	check(d.Tags.Get()[0] == "changed", "applied tags")
+
+	e := NewBuilder().Build()
+	e.Apply(c.Values().ToOptions()...)
+	check(reflect.DeepEqual(e.Values(), c.Values()), "values by options")
+}
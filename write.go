@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/tools/imports"
+)
+
+// outputFile is the generated file.
+type outputFile struct {
+	name    string
+	content []byte
+}
+
+// writeOutput writes b to fileName, or to stdout if fileName is empty.
+func writeOutput(fileName string, b []byte) error {
+	if fileName == "" {
+		_, err := os.Stdout.Write(b)
+		return err
+	}
+	_, err := writeFileIfChanged(fileName, b)
+	return err
+}
+
+// writeFiles writes the contents of files to w, each after the line "==> NAME <==" like head if there are multiple files.
+func writeFiles(w io.Writer, files []outputFile) error {
+	for i, f := range files {
+		if len(files) > 1 {
+			if i > 0 {
+				if _, err := fmt.Fprintln(w); err != nil {
+					return err
+				}
+			}
+			if _, err := fmt.Fprintf(w, "==> %s <==\n", f.name); err != nil {
+				return err
+			}
+		}
+		if _, err := w.Write(f.content); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// formatSource formats src and adds the missing imports like goimports.
+// fileName is the destination of src, to resolve the imports.
+func formatSource(src []byte, fileName string) ([]byte, error) {
+	b, err := imports.Process(fileName, src, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to goimport: %w", err)
+	}
+	return b, nil
+}
+
+// writeFileIfChanged writes b to fileName unless it already has the content, to keep the modification time.
+// The file is replaced by renaming a temporary file in the same directory, so it is never left half-written.
+// The mode of the existing file is kept, new files are 0644.
+// It reports whether the file is written.
+func writeFileIfChanged(fileName string, b []byte) (bool, error) {
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(fileName); err == nil {
+		mode = info.Mode().Perm()
+		if current, err := os.ReadFile(fileName); err == nil && bytes.Equal(current, b) {
+			return false, nil
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return false, err
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(fileName), "."+filepath.Base(fileName)+".*.tmp")
+	if err != nil {
+		return false, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(f.Name()) // fails after renamed
+
+	if _, err := f.Write(b); err != nil {
+		_ = f.Close()
+		return false, fmt.Errorf("failed to write to %s: %w", f.Name(), err)
+	}
+	if err := f.Chmod(mode); err != nil {
+		_ = f.Close()
+		return false, err
+	}
+	if err := f.Close(); err != nil {
+		return false, fmt.Errorf("failed to write to %s: %w", f.Name(), err)
+	}
+	if err := os.Rename(f.Name(), fileName); err != nil {
+		return false, fmt.Errorf("failed to write to %s: %w", fileName, err)
+	}
+	return true, nil
+}
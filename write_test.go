@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteFileIfChanged(t *testing.T) {
+	dir := t.TempDir()
+	p := filepath.Join(dir, "config.go")
+
+	written, err := writeFileIfChanged(p, []byte("package a\n"))
+	assert.Nil(t, err)
+	assert.True(t, written)
+	info, err := os.Stat(p)
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0644), info.Mode().Perm())
+
+	past := time.Now().Add(-time.Hour).Truncate(time.Second)
+	assert.Nil(t, os.Chtimes(p, past, past))
+	assert.Nil(t, os.Chmod(p, 0600))
+
+	t.Run("unchanged", func(t *testing.T) {
+		written, err := writeFileIfChanged(p, []byte("package a\n"))
+		assert.Nil(t, err)
+		assert.False(t, written)
+		info, err := os.Stat(p)
+		assert.Nil(t, err)
+		assert.True(t, info.ModTime().Equal(past))
+	})
+
+	t.Run("changed", func(t *testing.T) {
+		written, err := writeFileIfChanged(p, []byte("package b\n"))
+		assert.Nil(t, err)
+		assert.True(t, written)
+		b, err := os.ReadFile(p)
+		assert.Nil(t, err)
+		assert.Equal(t, "package b\n", string(b))
+		info, err := os.Stat(p)
+		assert.Nil(t, err)
+		assert.Equal(t, os.FileMode(0600), info.Mode().Perm(), "keep mode")
+	})
+
+	entries, err := os.ReadDir(dir)
+	assert.Nil(t, err)
+	assert.Len(t, entries, 1, "no temporary files")
+}
+
+func TestWriteFiles(t *testing.T) {
+	t.Run("single", func(t *testing.T) {
+		var buf bytes.Buffer
+		assert.Nil(t, writeFiles(&buf, []outputFile{{name: "config.go", content: []byte("package a\n")}}))
+		assert.Equal(t, "package a\n", buf.String())
+	})
+
+	t.Run("multiple", func(t *testing.T) {
+		var buf bytes.Buffer
+		assert.Nil(t, writeFiles(&buf, []outputFile{
+			{name: "config.go", content: []byte("package a\n")},
+			{name: "config_item.go", content: []byte("package a\n\ntype Item struct{}\n")},
+		}))
+		assert.Equal(t, `==> config.go <==
+package a
+
+==> config_item.go <==
+package a
+
+type Item struct{}
+`, buf.String())
+	})
+}
+
+func TestFormatSource(t *testing.T) {
+	got, err := formatSource([]byte("package a\nfunc F() string { return fmt.Sprint(1) }"), "a.go")
+	assert.Nil(t, err)
+	assert.Equal(t, `package a
+
+import "fmt"
+
+func F() string { return fmt.Sprint(1) }
+`, string(got))
+
+	_, err = formatSource([]byte("package a\nfunc F( {"), "a.go")
+	assert.NotNil(t, err)
+}